@@ -3,51 +3,19 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"strconv"
+	"os"
 
+	"github.com/briancain/devpod-provider-nomad/pkg/jobspec"
 	"github.com/briancain/devpod-provider-nomad/pkg/nomad"
 	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
 	"github.com/briancain/devpod-provider-nomad/pkg/vault"
-	"github.com/hashicorp/nomad/api"
 	"github.com/spf13/cobra"
 )
 
-const (
-	// Use Ubuntu as default since DevPod's Docker installation script supports it
-	// and we need Docker CLI for devcontainer support
-	defaultImage = "ubuntu:22.04"
-	defaultUser  = "root"
-)
-
 // CreateCmd holds the cmd flags
-type CreateCmd struct{}
-
-// buildGPUDeviceRequest creates a Nomad device request for NVIDIA GPUs
-func buildGPUDeviceRequest(options *opts.Options) *api.RequestedDevice {
-	count := uint64(options.GPUCount)
-	device := &api.RequestedDevice{
-		Name:  "nvidia/gpu",
-		Count: &count,
-	}
-	return device
-}
-
-// buildGPUJobConstraints returns job-level constraints for GPU workloads.
-// Compute capability uses a node meta attribute and must be a job constraint,
-// not a device constraint.
-func buildGPUJobConstraints(options *opts.Options) []*api.Constraint {
-	constraints := []*api.Constraint{
-		{LTarget: "${attr.cpu.arch}", Operand: "=", RTarget: "amd64"},
-		{LTarget: "${meta.gpu-dedicated}", Operand: "!=", RTarget: "true"},
-	}
-	if options.GPUComputeCapability != "" {
-		constraints = append(constraints, &api.Constraint{
-			LTarget: "${meta.gpu_compute_capability}",
-			Operand: ">=",
-			RTarget: options.GPUComputeCapability,
-		})
-	}
-	return constraints
+type CreateCmd struct {
+	DryRun bool
+	Render RenderCmd
 }
 
 // NewCommandCmd defines a command
@@ -57,14 +25,25 @@ func NewCreateCmd() *cobra.Command {
 		Use:   "create",
 		Short: "Create a new devpod instance on Nomad",
 		RunE: func(_ *cobra.Command, args []string) error {
+			if cmd.Render.Plan && !cmd.DryRun {
+				return fmt.Errorf("--plan requires --dry-run")
+			}
+
 			options, err := opts.FromEnv()
 			if err != nil {
 				return err
 			}
 
+			// Print what would be created instead of creating it
+			if cmd.DryRun {
+				return cmd.Render.Run(context.Background(), options, os.Stdout)
+			}
+
 			return cmd.Run(context.Background(), options)
 		},
 	}
+	commandCmd.Flags().BoolVar(&cmd.DryRun, "dry-run", false, "Print the job and CSI volume instead of creating them")
+	addRenderFlags(commandCmd, &cmd.Render)
 
 	return commandCmd
 }
@@ -78,268 +57,22 @@ func (cmd *CreateCmd) Run(
 		return err
 	}
 
-	// DevPod run option overrides for job
-	image := defaultImage
-	user := defaultUser
-	// Use a shared path that exists at the same location on both host and container
-	// This is critical for Docker-in-Docker bind mounts to work correctly
-	// Use a single shared directory for all DevPod workspaces
-	sharedWorkspacePath := "/tmp/devpod-workspaces"
-	env := map[string]string{}
-	entrypoint := ""
-	// Create shared workspace dir, install dependencies, combine Vault secrets into shared location
-	// Combine all vault secrets into /tmp/devpod-workspaces/.vault-secrets
-	// Start background process to copy secrets to workspace content directories as they're created
-	runCmd := []string{"/bin/sh", "-c", "mkdir -p " + sharedWorkspacePath + " && apt-get update -qq && apt-get install -y -qq curl git ca-certificates && update-ca-certificates && (for f in /secrets/vault-*.env; do [ -f \"$f\" ] && cat \"$f\" >> " + sharedWorkspacePath + "/.vault-secrets; done || true) && sleep 2 && touch /tmp/.devpod-ready && (while true; do find " + sharedWorkspacePath + "/agent/contexts/*/workspaces/*/content -maxdepth 0 -type d 2>/dev/null | while read wsdir; do if [ -f " + sharedWorkspacePath + "/.vault-secrets ] && [ ! -f \"$wsdir/.vault-secrets\" ]; then cp " + sharedWorkspacePath + "/.vault-secrets \"$wsdir/.vault-secrets\" && chmod 644 \"$wsdir/.vault-secrets\"; fi; done; sleep 5; done) & sleep infinity"}
-	if options.DriverOpts != nil {
-		if options.DriverOpts.Image != "" {
-			image = options.DriverOpts.Image
-		}
-		if options.DriverOpts.User != "" {
-			user = options.DriverOpts.User
-		}
-		if options.DriverOpts.Env != nil {
-			// Merge user env vars with our required env vars (ours take precedence)
-			for k, v := range options.DriverOpts.Env {
-				if _, exists := env[k]; !exists {
-					env[k] = v
-				}
-			}
-		}
-		if options.DriverOpts.Entrypoint != "" {
-			entrypoint = options.DriverOpts.Entrypoint
-		}
-		if options.DriverOpts.Cmd != nil {
-			runCmd = append([]string{entrypoint}, options.DriverOpts.Cmd...)
-		}
-	} // err if nil?
-
-	// For persistent storage mode, modify runCmd to sync between /persistent and /tmp/devpod-workspaces
-	if options.StorageMode == opts.StorageModePersistent {
-		// Build a command that:
-		// 1. Installs rsync
-		// 2. Restores data from /persistent to /tmp/devpod-workspaces on startup
-		// 3. Runs background sync every 60 seconds
-		// 4. Sets up exit trap for final sync
-		runCmd = []string{"/bin/sh", "-c", `
-mkdir -p /tmp/devpod-workspaces /persistent
-
-# Install rsync for efficient syncing
-apt-get update -qq && apt-get install -y -qq curl git ca-certificates rsync && update-ca-certificates
-
-# Restore from persistent storage if it has data
-if [ -d /persistent/agent ] && [ "$(ls -A /persistent/agent 2>/dev/null)" ]; then
-  echo "Restoring workspace from persistent storage..."
-  rsync -a /persistent/ /tmp/devpod-workspaces/
-fi
-
-# Combine vault secrets
-for f in /secrets/vault-*.env; do [ -f "$f" ] && cat "$f" >> /tmp/devpod-workspaces/.vault-secrets; done || true
-
-# Mark as ready
-sleep 2 && touch /tmp/.devpod-ready
-
-# Background process: copy secrets to workspace content directories
-(while true; do
-  find /tmp/devpod-workspaces/agent/contexts/*/workspaces/*/content -maxdepth 0 -type d 2>/dev/null | while read wsdir; do
-    if [ -f /tmp/devpod-workspaces/.vault-secrets ] && [ ! -f "$wsdir/.vault-secrets" ]; then
-      cp /tmp/devpod-workspaces/.vault-secrets "$wsdir/.vault-secrets" && chmod 644 "$wsdir/.vault-secrets"
-    fi
-  done
-  sleep 5
-done) &
-
-# Background process: sync to persistent storage every 60 seconds
-(while true; do
-  sleep 60
-  rsync -a --delete /tmp/devpod-workspaces/ /persistent/ 2>/dev/null || true
-done) &
-
-# Set up exit trap for final sync
-trap 'echo "Syncing to persistent storage..."; rsync -a --delete /tmp/devpod-workspaces/ /persistent/' EXIT
-
-# Keep container running
-sleep infinity
-`}
-	}
-
-	cpu, err := strconv.Atoi(options.CPU)
-	if err != nil {
-		return err
-	}
-	mem, err := strconv.Atoi(options.MemoryMB)
-	if err != nil {
-		return err
-	}
-	disk, err := strconv.Atoi(options.DiskMB)
+	// Build the job first so invalid options or a broken job template never leave
+	// an orphaned CSI volume behind
+	job, err := buildJob(ctx, options)
 	if err != nil {
 		return err
 	}
 
 	// For persistent storage, create CSI volume if it doesn't exist
-	var volumeID string
 	if options.StorageMode == opts.StorageModePersistent {
-		volumeID = options.GetVolumeID()
-
-		// Convert MB to bytes for CSI volume capacity
-		capacityBytes := int64(disk) * 1024 * 1024
-
-		// Check if volume already exists
-		exists, err := nomadClient.VolumeExists(ctx, volumeID, options.Namespace)
-		if err != nil {
-			return fmt.Errorf("failed to check if volume exists: %w", err)
-		}
-
-		if !exists {
-			// Fetch CSI secrets from Vault
-			csiSecrets, err := fetchCSISecretsFromVault(options)
-			if err != nil {
-				return fmt.Errorf("failed to fetch CSI secrets from Vault: %w", err)
-			}
-
-			err = nomadClient.CreateCSIVolume(
-				ctx,
-				volumeID,
-				capacityBytes,
-				options.CSIPluginID,
-				options.CSIClusterID,
-				options.CSIPool,
-				options.Namespace,
-				csiSecrets,
-			)
-			if err != nil {
-				return err
-			}
+		if err := ensureCSIVolume(ctx, nomadClient, options); err != nil {
+			return err
 		}
 	}
 
-	jobResources := &api.Resources{
-		CPU:      &cpu,
-		MemoryMB: &mem,
-	}
-
-	// Add GPU device request if enabled
-	if options.GPUEnabled {
-		gpuDevice := buildGPUDeviceRequest(options)
-		jobResources.Devices = []*api.RequestedDevice{gpuDevice}
-	}
-
-	// Use the machine ID for job name and task group name
-	jobName := options.JobId
-
-	// Build Docker volumes list
-	// Mount Docker socket from host for Docker-in-Docker support
-	// Mount Docker registry certificates for Docker daemon
-	// Mount CA certificate source file so update-ca-certificates includes it
-	dockerVolumes := []string{
-		"/var/run/docker.sock:/var/run/docker.sock",
-		"/etc/docker/certs.d:/etc/docker/certs.d:ro",
-		"/usr/local/share/ca-certificates/registry.cluster.crt:/usr/local/share/ca-certificates/registry.cluster.crt:ro",
-	}
-
-	// Always include host bind mount for Docker-in-Docker compatibility
-	// Docker looks for bind mount paths on the HOST, so we need this path to exist on the host
-	// For persistent mode, we sync data between this path and the CSI volume at /persistent
-	dockerVolumes = append(dockerVolumes, sharedWorkspacePath+":"+sharedWorkspacePath)
-
-	// Create the base task
-	task := &api.Task{
-		Name: options.TaskName,
-		User: user,
-		Env:  env,
-		Config: map[string]interface{}{
-			"image":        image,
-			"args":         runCmd,
-			"volumes":      dockerVolumes,
-			"privileged":   true,
-			"network_mode": "bridge",
-		},
-		Resources: jobResources,
-		Driver:    "docker",
-	}
-
-	// Configure GPU support if enabled
-	if options.GPUEnabled {
-		task.Config["runtime"] = "nvidia"
-		task.Config["shm_size"] = int64(2147483648) // 2GB shared memory for ML workloads
-		if task.Env == nil {
-			task.Env = make(map[string]string)
-		}
-		task.Env["NVIDIA_VISIBLE_DEVICES"] = "all"
-		task.Env["NVIDIA_DRIVER_CAPABILITIES"] = "compute,utility"
-	}
-
-	// Add Vault integration if configured
-	if len(options.VaultSecrets) > 0 {
-		task.Vault = &api.Vault{
-			Policies:   options.VaultPolicies,
-			ChangeMode: &options.VaultChangeMode,
-		}
-
-		// Set optional Vault fields if provided
-		if options.VaultRole != "" {
-			task.Vault.Role = options.VaultRole
-		}
-		if options.VaultNamespace != "" {
-			task.Vault.Namespace = &options.VaultNamespace
-		}
-
-		// Generate and attach Vault secret templates
-		task.Templates = generateVaultTemplates(options.VaultSecrets, options.VaultChangeMode)
-	}
-
-	// Build task group with appropriate storage configuration
-	taskGroup := &api.TaskGroup{
-		Name:  &jobName,
-		Tasks: []*api.Task{task},
-	}
-
-	if options.StorageMode == opts.StorageModePersistent {
-		// Use CSI volume for persistent storage
-		// Mount at /persistent, sync with /tmp/devpod-workspaces for Docker-in-Docker compatibility
-		volumeName := "workspace"
-		persistentMountPath := "/persistent"
-		readOnly := false
-
-		taskGroup.Volumes = map[string]*api.VolumeRequest{
-			volumeName: {
-				Name:           volumeName,
-				Type:           "csi",
-				Source:         volumeID,
-				AccessMode:     string(api.CSIVolumeAccessModeSingleNodeWriter),
-				AttachmentMode: string(api.CSIVolumeAttachmentModeFilesystem),
-				MountOptions: &api.CSIMountOptions{
-					FSType: "ext4",
-				},
-			},
-		}
-
-		// Add volume mount to task
-		task.VolumeMounts = []*api.VolumeMount{
-			{
-				Volume:      &volumeName,
-				Destination: &persistentMountPath,
-				ReadOnly:    &readOnly,
-			},
-		}
-	} else {
-		// Use ephemeral disk for non-persistent storage
-		taskGroup.EphemeralDisk = &api.EphemeralDisk{
-			SizeMB: &disk,
-		}
-	}
-
-	job := &api.Job{
-		ID:         &options.JobId,
-		Name:       &jobName,
-		Namespace:  &options.Namespace,
-		Region:     &options.Region,
-		TaskGroups: []*api.TaskGroup{taskGroup},
-	}
-
-	// Add GPU-specific job constraints
-	if options.GPUEnabled {
-		job.Constraints = append(job.Constraints, buildGPUJobConstraints(options)...)
+	if err := putRegistryVariable(ctx, nomadClient, options, options.JobId); err != nil {
+		return err
 	}
 
 	_, err = nomadClient.Create(ctx, job)
@@ -350,42 +83,29 @@ sleep infinity
 	return nil
 }
 
-// generateVaultTemplates creates Nomad template stanzas for Vault secrets
-func generateVaultTemplates(secrets []opts.VaultSecret, changeMode string) []*api.Template {
-	if len(secrets) == 0 {
-		return nil
+// ensureCSIVolume creates the workspace's CSI volume unless it already exists
+func ensureCSIVolume(ctx context.Context, nomadClient *nomad.Nomad, options *opts.Options) error {
+	vol, err := jobspec.BuildCSIVolume(options)
+	if err != nil {
+		return err
 	}
 
-	templates := make([]*api.Template, len(secrets))
-	for i, secret := range secrets {
-		tmpl := generateSecretTemplate(secret)
-		destPath := "secrets/vault-" + strconv.Itoa(i) + ".env"
-		templates[i] = &api.Template{
-			DestPath:     &destPath,
-			EmbeddedTmpl: &tmpl,
-			Envvars:      boolPtr(true), // Makes secrets available as environment variables
-			ChangeMode:   &changeMode,
-		}
+	// Check if volume already exists
+	exists, err := nomadClient.VolumeExists(ctx, vol.ID, options.Namespace)
+	if err != nil {
+		return fmt.Errorf("failed to check if volume exists: %w", err)
 	}
-
-	return templates
-}
-
-// generateSecretTemplate creates a Nomad template string for a single Vault secret
-func generateSecretTemplate(secret opts.VaultSecret) string {
-	template := "{{- with secret \"" + secret.Path + "\" -}}\n"
-
-	for vaultField, envVar := range secret.Fields {
-		template += "export " + envVar + "=\"{{ .Data.data." + vaultField + " }}\"\n"
+	if exists {
+		return nil
 	}
 
-	template += "{{- end }}\n"  // Don't strip trailing whitespace to preserve newlines
-	return template
-}
+	// Fetch CSI secrets from Vault
+	csiSecrets, err := fetchCSISecretsFromVault(options)
+	if err != nil {
+		return fmt.Errorf("failed to fetch CSI secrets from Vault: %w", err)
+	}
 
-// boolPtr returns a pointer to a bool value
-func boolPtr(b bool) *bool {
-	return &b
+	return nomadClient.CreateCSIVolume(ctx, vol, csiSecrets)
 }
 
 // fetchCSISecretsFromVault fetches CSI credentials from Vault
@@ -396,12 +116,15 @@ func fetchCSISecretsFromVault(options *opts.Options) (*nomad.CSISecrets, error)
 		return nil, fmt.Errorf("failed to create Vault client: %w", err)
 	}
 
-	// Get token from environment (DEVPOD_VAULT_TOKEN)
-	token := vault.GetTokenFromEnv()
-	if token == "" {
-		return nil, fmt.Errorf("DEVPOD_VAULT_TOKEN environment variable is required for fetching CSI secrets")
+	// Authenticate with the configured method (token is cached for this command)
+	err = vaultClient.Login(vault.AuthConfig{
+		Method: options.VaultAuthMethod,
+		Mount:  options.VaultAuthMount,
+		Role:   options.VaultAuthRole,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate to Vault: %w", err)
 	}
-	vaultClient.SetToken(token)
 
 	// Fetch CSI secrets from Vault
 	secrets, err := vaultClient.ReadCSISecrets(options.CSIVaultPath)
@@ -2,13 +2,20 @@ package cmd
 
 import (
 	"context"
+	"fmt"
+	"time"
 
+	"github.com/briancain/devpod-provider-nomad/pkg/jobspec"
 	"github.com/briancain/devpod-provider-nomad/pkg/nomad"
 	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+	"github.com/hashicorp/nomad/api"
 	"github.com/loft-sh/log"
 	"github.com/spf13/cobra"
 )
 
+// Time the cleanup job gets to remove the workspace directory
+const cleanupTimeout = 5 * time.Minute
+
 // DeleteCmd holds the cmd flags
 type DeleteCmd struct{}
 
@@ -40,15 +47,35 @@ func (cmd *DeleteCmd) Run(
 		return err
 	}
 
-	// First delete the job
+	logger := log.Default.ErrorStreamOnly()
+
+	// The workspace directory is bind-mounted from the Nomad client. Stop the workspace
+	// first so its final sync to persistent storage still sees the files, then remove
+	// the directory from every node it ran on, even if the workspace had crashed.
+	if options.UsesHostDocker() {
+		nodes, err := nomadClient.Stop(ctx, options.JobId, cleanupStopTimeout(options))
+		if err != nil {
+			logger.Warnf("Failed to stop workspace %s before removing its directory: %v (%s may need manual cleanup on the Nomad client)", options.JobId, err, options.WorkspacePath())
+		}
+		for _, node := range nodes {
+			if err := cleanWorkspaceDir(ctx, nomadClient, options, node); err != nil {
+				logger.Warnf("Failed to remove workspace directory %s from node %s: %v (the directory may need manual cleanup on the Nomad client)", options.WorkspacePath(), node.Name, err)
+			}
+		}
+	}
+
+	// Then delete the job
 	if err := nomadClient.Delete(ctx, options.JobId); err != nil {
 		return err
 	}
 
+	if err := deleteRegistryVariable(ctx, nomadClient, options, options.JobId); err != nil {
+		logger.Warnf("Failed to delete the registry credentials in Nomad Variable %s: %v", jobspec.RegistryVariablePath(options.JobId), err)
+	}
+
 	// If persistent storage mode, also delete the CSI volume
 	if options.StorageMode == opts.StorageModePersistent {
 		volumeID := options.GetVolumeID()
-		logger := log.Default.ErrorStreamOnly()
 
 		// Delete CSI volume - log warning but don't fail if this fails
 		// The volume might have already been deleted or might still be detaching
@@ -59,3 +86,22 @@ func (cmd *DeleteCmd) Run(
 
 	return nil
 }
+
+// cleanupStopTimeout returns how long to wait for the workspace to stop: its kill
+// timeout, which bounds the final sync, plus time for Nomad to stop the task
+func cleanupStopTimeout(options *opts.Options) time.Duration {
+	killTimeout, err := time.ParseDuration(options.KillTimeout)
+	if err != nil {
+		killTimeout = 0
+	}
+	return killTimeout + time.Minute
+}
+
+// cleanWorkspaceDir removes the workspace directory from node with a batch job
+// pinned to it
+func cleanWorkspaceDir(ctx context.Context, nomadClient *nomad.Nomad, options *opts.Options, node *api.Node) error {
+	jobID := fmt.Sprintf("%s-cleanup-%d", options.JobId, time.Now().Unix())
+	job := jobspec.BuildCleanupJob(options, jobID, node)
+	_, err := runBatchJob(ctx, nomadClient, options, job, jobspec.CleanupTaskName, cleanupTimeout)
+	return err
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/briancain/devpod-provider-nomad/pkg/jobspec"
+	"github.com/briancain/devpod-provider-nomad/pkg/nomad"
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+	"github.com/spf13/cobra"
+)
+
+// Time the doctor check gets to pull the image and run
+const doctorTimeout = 10 * time.Minute
+
+// DoctorCmd holds the cmd flags
+type DoctorCmd struct {
+	Image string
+}
+
+// NewDoctorCmd defines a command
+func NewDoctorCmd() *cobra.Command {
+	cmd := &DoctorCmd{}
+	doctorCmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check on Nomad that an image has the tools a workspace needs",
+		RunE: func(_ *cobra.Command, args []string) error {
+			options, err := opts.FromEnv()
+			if err != nil {
+				return err
+			}
+
+			return cmd.Run(context.Background(), options, os.Stdout)
+		},
+	}
+	doctorCmd.Flags().StringVar(&cmd.Image, "image", "", "Image to check, defaults to the workspace image")
+
+	return doctorCmd
+}
+
+// Run checks the image with a short batch job using the workspace's task driver,
+// placement and registry credentials, and reports the missing tools
+func (cmd *DoctorCmd) Run(
+	ctx context.Context,
+	options *opts.Options,
+	w io.Writer,
+) error {
+	image := cmd.Image
+	if image == "" {
+		image = jobspec.DoctorImage(options)
+	}
+
+	nomadClient, err := nomad.NewNomad(options)
+	if err != nil {
+		return err
+	}
+
+	jobID := fmt.Sprintf("%s-doctor-%d", options.JobId, time.Now().Unix())
+	job := jobspec.BuildDoctorJob(options, jobID, image)
+
+	fmt.Fprintf(w, "Checking %s with job %s...\n", image, jobID)
+	output, err := runBatchJob(ctx, nomadClient, options, job, options.TaskName, doctorTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to check image %s: %w", image, err)
+	}
+
+	tools, err := jobspec.ParseDoctorOutput(options, output)
+	if err != nil {
+		return fmt.Errorf("failed to check image %s: %w", image, err)
+	}
+
+	var missing []string
+	for _, tool := range tools {
+		if tool.Missing {
+			fmt.Fprintf(w, "  missing  %s (package %s)\n", tool.Command, tool.Package)
+			if !slices.Contains(missing, tool.Package) {
+				missing = append(missing, tool.Package)
+			}
+		} else {
+			fmt.Fprintf(w, "  ok       %s\n", tool.Command)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("image %s is missing required tools (install %s, or use NOMAD_BOOTSTRAP_MODE=%s or %s)", image, strings.Join(missing, " "), opts.BootstrapModeAuto, opts.BootstrapModeInstall)
+	}
+	fmt.Fprintf(w, "Image %s has every tool the workspace needs and can be used with NOMAD_BOOTSTRAP_MODE=%s\n", image, opts.BootstrapModePrebuilt)
+	return nil
+}
@@ -44,6 +44,17 @@ func (cmd *InitCmd) Run(
 		return err
 	}
 
+	// Catch a misspelled node pool here rather than with a job that never gets placed
+	if options.NodePool != "" {
+		exists, err := nomad.NodePoolExists(ctx, options.NodePool)
+		if err != nil {
+			return fmt.Errorf("failed to check node pool %s: %w", options.NodePool, err)
+		}
+		if !exists {
+			return fmt.Errorf("node pool %s does not exist", options.NodePool)
+		}
+	}
+
 	fmt.Println("Nomad is ready")
 	return nil
 }
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/briancain/devpod-provider-nomad/pkg/jobspec"
+	"github.com/briancain/devpod-provider-nomad/pkg/nomad"
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+	"github.com/hashicorp/nomad/api"
+)
+
+// putRegistryVariable stores the registry credentials set in the provider options
+// in the Nomad Variable the tasks of job jobID read them from, so they are not
+// written into the job
+func putRegistryVariable(ctx context.Context, nomadClient *nomad.Nomad, options *opts.Options, jobID string) error {
+	variable := jobspec.RegistryVariable(options, jobID)
+	if variable == nil {
+		return nil
+	}
+	if err := nomadClient.PutVariable(ctx, variable); err != nil {
+		return fmt.Errorf("failed to store registry credentials in Nomad Variable %s: %w", variable.Path, err)
+	}
+	return nil
+}
+
+// deleteRegistryVariable deletes the Nomad Variable written by putRegistryVariable
+func deleteRegistryVariable(ctx context.Context, nomadClient *nomad.Nomad, options *opts.Options, jobID string) error {
+	variable := jobspec.RegistryVariable(options, jobID)
+	if variable == nil {
+		return nil
+	}
+	return nomadClient.DeleteVariable(ctx, variable.Path, variable.Namespace)
+}
+
+// runBatchJob runs a short-lived batch job with the registry credentials it needs,
+// removing them again once the job is done
+func runBatchJob(ctx context.Context, nomadClient *nomad.Nomad, options *opts.Options, job *api.Job, taskName string, timeout time.Duration) (string, error) {
+	if err := putRegistryVariable(ctx, nomadClient, options, *job.ID); err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = deleteRegistryVariable(ctx, nomadClient, options, *job.ID)
+	}()
+
+	return nomadClient.RunBatchJob(ctx, job, taskName, timeout)
+}
+
+// buildJob builds the workspace job and merges the user's job template into it
+func buildJob(ctx context.Context, options *opts.Options) (*api.Job, error) {
+	job, err := jobspec.Build(options)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.JobTemplate == "" {
+		return job, nil
+	}
+
+	tmpl, err := loadJobTemplate(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	if err := jobspec.ApplyJobTemplate(job, tmpl); err != nil {
+		return nil, fmt.Errorf("apply job template %s: %w", options.JobTemplate, err)
+	}
+
+	return job, nil
+}
+
+// loadJobTemplate reads the job template. JSON is parsed locally, HCL by the Nomad server
+// so templates can use any jobspec feature the cluster's Nomad version supports.
+func loadJobTemplate(ctx context.Context, options *opts.Options) (*api.Job, error) {
+	data, err := os.ReadFile(options.JobTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("read job template: %w", err)
+	}
+
+	if strings.EqualFold(filepath.Ext(options.JobTemplate), ".json") {
+		return jobspec.ParseJSONJobTemplate(data)
+	}
+
+	nomadClient, err := nomad.NewNomad(options)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := nomadClient.ParseJobHCL(ctx, string(data))
+	if err != nil {
+		return nil, fmt.Errorf("parse job template %s: %w", options.JobTemplate, err)
+	}
+
+	return tmpl, nil
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/briancain/devpod-provider-nomad/pkg/jobspec"
+	"github.com/briancain/devpod-provider-nomad/pkg/nomad"
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+	"github.com/hashicorp/nomad/api"
+	"github.com/spf13/cobra"
+)
+
+const (
+	outputJSON = "json"
+	outputHCL  = "hcl"
+)
+
+// RenderCmd holds the cmd flags
+type RenderCmd struct {
+	Output string
+	Plan   bool
+}
+
+// renderedJob is the JSON output of render. The "Job" key matches the format
+// accepted by `nomad job run -json`.
+type renderedJob struct {
+	Job    *api.Job             `json:"Job"`
+	Volume *api.CSIVolume       `json:"Volume,omitempty"`
+	Plan   *api.JobPlanResponse `json:"Plan,omitempty"`
+}
+
+// NewRenderCmd defines a command
+func NewRenderCmd() *cobra.Command {
+	cmd := &RenderCmd{}
+	renderCmd := &cobra.Command{
+		Use:   "render",
+		Short: "Print the Nomad job for a devpod instance without submitting it",
+		Long: `Print the Nomad job for a devpod instance without submitting it.
+
+Nothing is registered or created. Nomad is only contacted for --plan and to parse
+an HCL NOMAD_JOB_TEMPLATE, which need a reachable server (NOMAD_ADDR and NOMAD_TOKEN).
+JSON job templates are parsed locally.`,
+		RunE: func(_ *cobra.Command, args []string) error {
+			options, err := opts.FromEnv()
+			if err != nil {
+				return err
+			}
+
+			return cmd.Run(context.Background(), options, os.Stdout)
+		},
+	}
+	addRenderFlags(renderCmd, cmd)
+
+	return renderCmd
+}
+
+func addRenderFlags(command *cobra.Command, cmd *RenderCmd) {
+	command.Flags().StringVarP(&cmd.Output, "output", "o", outputJSON, "Output format: json or hcl")
+	command.Flags().BoolVar(&cmd.Plan, "plan", false, "Run a Nomad plan to show the job diff and placement feasibility")
+}
+
+// Run writes the job, and the CSI volume in persistent storage mode, to w.
+// Nothing is registered or created. Nomad is only contacted for the plan and to
+// parse an HCL job template.
+func (cmd *RenderCmd) Run(
+	ctx context.Context,
+	options *opts.Options,
+	w io.Writer,
+) error {
+	if cmd.Output != outputJSON && cmd.Output != outputHCL {
+		return fmt.Errorf("invalid output format: %s (must be %s or %s)", cmd.Output, outputJSON, outputHCL)
+	}
+
+	job, err := buildJob(ctx, options)
+	if err != nil {
+		return err
+	}
+
+	var volume *api.CSIVolume
+	if options.StorageMode == opts.StorageModePersistent {
+		volume, err = jobspec.BuildCSIVolume(options)
+		if err != nil {
+			return err
+		}
+	}
+
+	var plan *api.JobPlanResponse
+	volumeMissing := false
+	if cmd.Plan {
+		nomadClient, err := nomad.NewNomad(options)
+		if err != nil {
+			return err
+		}
+
+		if volume != nil {
+			exists, err := nomadClient.VolumeExists(ctx, volume.ID, options.Namespace)
+			if err != nil {
+				return fmt.Errorf("failed to check if volume exists: %w", err)
+			}
+			volumeMissing = !exists
+		}
+
+		plan, err = nomadClient.Plan(ctx, job)
+		if err != nil {
+			return fmt.Errorf("failed to plan job %q: %w", options.JobId, err)
+		}
+	}
+
+	if cmd.Output == outputJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(renderedJob{Job: job, Volume: volume, Plan: plan})
+	}
+
+	if volume != nil {
+		fmt.Fprintf(w, "# CSI volume spec, created before the job is registered\n%s\n", jobspec.RenderVolumeHCL(volume))
+	}
+	fmt.Fprintf(w, "# Nomad job spec\n%s", jobspec.RenderHCL(job))
+	if plan != nil {
+		fmt.Fprintf(w, "\n# Plan\n")
+		if volumeMissing {
+			fmt.Fprintf(w, "Note: CSI volume %s does not exist yet and would be created by create; until then the scheduler cannot place the job.\n\n", volume.ID)
+		}
+		fmt.Fprint(w, nomad.FormatPlan(plan))
+	}
+
+	return nil
+}
@@ -19,6 +19,8 @@ func Execute() {
 	rootCmd.AddCommand(NewCreateCmd())
 	rootCmd.AddCommand(NewDeleteCmd())
 	rootCmd.AddCommand(NewStatusCmd())
+	rootCmd.AddCommand(NewRenderCmd())
+	rootCmd.AddCommand(NewDoctorCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		// TODO: handle this more gracefully
@@ -2,16 +2,28 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/briancain/devpod-provider-nomad/pkg/nomad"
 	"github.com/briancain/devpod-provider-nomad/pkg/options"
+	"github.com/hashicorp/nomad/api"
+	"github.com/loft-sh/devpod/pkg/client"
 	"github.com/spf13/cobra"
 )
 
 // StatusCmd holds the cmd flags
-type StatusCmd struct{}
+type StatusCmd struct {
+	Output string
+}
+
+// statusOutput is the JSON output of status
+type statusOutput struct {
+	Status client.Status     `json:"Status"`
+	Ports  []api.PortMapping `json:"Ports"`
+}
 
 // NewCommandCmd defines a command
 func NewStatusCmd() *cobra.Command {
@@ -25,9 +37,11 @@ func NewStatusCmd() *cobra.Command {
 				return err
 			}
 
-			return cmd.Run(context.Background(), options)
+			return cmd.Run(context.Background(), options, os.Stdout)
 		},
 	}
+	// DevPod reads the plain status, so JSON is opt-in
+	commandCmd.Flags().StringVarP(&cmd.Output, "output", "o", "", "Output format: json to include the host ports of the workspace")
 
 	return commandCmd
 }
@@ -35,7 +49,12 @@ func NewStatusCmd() *cobra.Command {
 func (cmd *StatusCmd) Run(
 	ctx context.Context,
 	options *options.Options,
+	w io.Writer,
 ) error {
+	if cmd.Output != "" && cmd.Output != outputJSON {
+		return fmt.Errorf("invalid output format: %s (must be %s)", cmd.Output, outputJSON)
+	}
+
 	nomad, err := nomad.NewNomad(options)
 	if err != nil {
 		return err
@@ -46,7 +65,20 @@ func (cmd *StatusCmd) Run(
 		return fmt.Errorf("failed to get status of job %q: %w", options.JobId, err)
 	}
 
-	_, err = fmt.Fprint(os.Stdout, status)
+	if cmd.Output != outputJSON {
+		_, err = fmt.Fprint(w, status)
+		return err
+	}
+
+	ports, err := nomad.Ports(ctx, options.JobId)
+	if err != nil {
+		return fmt.Errorf("failed to get ports of job %q: %w", options.JobId, err)
+	}
+	if ports == nil {
+		ports = []api.PortMapping{}
+	}
 
-	return nil
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(statusOutput{Status: status, Ports: ports})
 }
@@ -0,0 +1,49 @@
+package jobspec
+
+import (
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+	"github.com/hashicorp/nomad/api"
+)
+
+// Resources of the tasks of short-lived batch jobs run next to the workspace
+const (
+	batchCPU      = 100
+	batchMemoryMB = 128
+)
+
+// buildBatchJob returns a batch job in the workspace's namespace and region running
+// task once. A failed run is reported, not retried.
+func buildBatchJob(options *opts.Options, jobID string, task *api.Task) *api.Job {
+	cpu := batchCPU
+	mem := batchMemoryMB
+	task.Resources = &api.Resources{
+		CPU:      &cpu,
+		MemoryMB: &mem,
+	}
+
+	attempts := 0
+	mode := "fail"
+	unlimited := false
+	group := &api.TaskGroup{
+		Name:  &jobID,
+		Tasks: []*api.Task{task},
+		RestartPolicy: &api.RestartPolicy{
+			Attempts: &attempts,
+			Mode:     &mode,
+		},
+		ReschedulePolicy: &api.ReschedulePolicy{
+			Attempts:  &attempts,
+			Unlimited: &unlimited,
+		},
+	}
+
+	jobType := api.JobTypeBatch
+	return &api.Job{
+		ID:         &jobID,
+		Name:       &jobID,
+		Type:       &jobType,
+		Namespace:  &options.Namespace,
+		Region:     &options.Region,
+		TaskGroups: []*api.TaskGroup{group},
+	}
+}
@@ -0,0 +1,359 @@
+package jobspec
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+)
+
+const (
+	// Mount point of the CSI volume in persistent storage mode
+	persistentMountPath = "/persistent"
+
+	// Marker file checked by the provider before running commands in the task
+	readyMarkerPath = "/tmp/.devpod-ready"
+
+	// Rendered templates (Vault, Nomad Variables and Consul) combined into .vault-secrets
+	secretsTemplateFiles = "/secrets/vault-*.env /secrets/nomadvar-*.env /secrets/consul.env"
+)
+
+// bootstrapTool is a command the workspace needs and the Debian package providing it
+type bootstrapTool struct {
+	command string
+	pkg     string
+}
+
+// bootstrapTools returns the commands the bootstrap script and DevPod need in the image
+func bootstrapTools(options *opts.Options) []bootstrapTool {
+	tools := []bootstrapTool{
+		{"curl", "curl"},
+		{"git", "git"},
+		{"update-ca-certificates", "ca-certificates"},
+	}
+	if workspaceDriver(options).nestedDocker() {
+		tools = append(tools, bootstrapTool{"dockerd", "docker.io"}, bootstrapTool{"docker", "docker.io"})
+	}
+	if options.StorageMode == opts.StorageModePersistent {
+		tools = append(tools, bootstrapTool{"rsync", "rsync"})
+	}
+	if user := commandUser(options); user != "" {
+		tools = append(tools, bootstrapTool{userSwitchCommand(user), "util-linux"})
+	}
+	return tools
+}
+
+// commandUser returns the devcontainer's user the workspace command runs as, or ""
+// when it runs as root like the rest of the bootstrap script
+func commandUser(options *opts.Options) string {
+	if options.DriverOpts == nil {
+		return ""
+	}
+	switch user := options.DriverOpts.User; user {
+	case "", "root", "0", "root:root", "0:0":
+		return ""
+	default:
+		return user
+	}
+}
+
+// userSwitchCommand returns the command the bootstrap script runs the workspace
+// command with as user: runuser for a user name, which sets up the user's groups and
+// home directory like docker exec -u, and setpriv for a user:group pair
+func userSwitchCommand(user string) string {
+	if strings.Contains(user, ":") {
+		return "setpriv"
+	}
+	return "runuser"
+}
+
+// shellQuote quotes s as a single shell word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// writeToolChecks writes shell lines collecting the commands missing from the image
+// in $missing and their packages in $packages
+func writeToolChecks(b *strings.Builder, tools []bootstrapTool) {
+	b.WriteString("missing=\"\"\npackages=\"\"\n")
+	for _, tool := range tools {
+		b.WriteString(`command -v ` + tool.command + ` > /dev/null 2>&1 || { missing="$missing ` + tool.command + `"; packages="$packages ` + tool.pkg + `"; }` + "\n")
+	}
+}
+
+// writeToolSetup writes the part of the bootstrap script that makes sure the image
+// has the tools the workspace needs, depending on the bootstrap mode. The script
+// exits when they can't be installed, before the workspace is marked ready.
+func writeToolSetup(b *strings.Builder, options *opts.Options) {
+	tools := bootstrapTools(options)
+
+	switch options.BootstrapMode {
+	case opts.BootstrapModePrebuilt:
+		b.WriteString("# Check the tools the prebuilt image must bring\n")
+		writeToolChecks(b, tools)
+		b.WriteString(`if [ -n "$missing" ]; then
+  echo "The image is missing required tools:$missing (NOMAD_BOOTSTRAP_MODE=` + opts.BootstrapModePrebuilt + `)" >&2
+  exit 1
+fi
+update-ca-certificates || exit 1
+
+`)
+	case opts.BootstrapModeAuto:
+		b.WriteString("# Install the tools missing from the image\n")
+		writeToolChecks(b, tools)
+		b.WriteString(`if [ -n "$packages" ]; then
+  echo "Installing missing tools:$missing"
+  apt-get update -qq && apt-get install -y -qq$packages || exit 1
+fi
+update-ca-certificates || exit 1
+
+`)
+	default:
+		var packages []string
+		for _, tool := range tools {
+			if !slices.Contains(packages, tool.pkg) {
+				packages = append(packages, tool.pkg)
+			}
+		}
+		if options.StorageMode == opts.StorageModePersistent {
+			b.WriteString("# Install dependencies (rsync for efficient syncing)\n")
+		} else {
+			b.WriteString("# Install dependencies\n")
+		}
+		b.WriteString("apt-get update -qq && apt-get install -y -qq " + strings.Join(packages, " ") + " && update-ca-certificates || exit 1\n\n")
+	}
+}
+
+// liveSecretRotation reports whether any rendered secret template can change while
+// the task keeps running (noop or signal change mode). In that case the bootstrap
+// watches the templates and refreshes the aggregated secrets files in place.
+func liveSecretRotation(options *opts.Options) bool {
+	for _, secret := range options.VaultSecrets {
+		if options.SecretChangeMode(secret) != "restart" {
+			return true
+		}
+	}
+	sharedTemplates := len(options.NomadVariables) > 0 || len(options.ConsulKV) > 0 || len(options.ConsulServices) > 0
+	return sharedTemplates && options.VaultChangeMode != "restart"
+}
+
+// secretRefreshSignals returns the trap names (without the SIG prefix) of the signals
+// Nomad may send on a template change. HUP is always included so an operator can
+// force a refresh with "nomad alloc signal".
+func secretRefreshSignals(options *opts.Options) []string {
+	signals := map[string]bool{"HUP": true}
+	if options.VaultChangeMode == "signal" {
+		signals[strings.TrimPrefix(options.VaultChangeSignal, "SIG")] = true
+	}
+	for _, secret := range options.VaultSecrets {
+		if options.SecretChangeMode(secret) == "signal" {
+			signals[strings.TrimPrefix(options.SecretChangeSignal(secret), "SIG")] = true
+		}
+	}
+
+	names := make([]string, 0, len(signals))
+	for name := range signals {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// buildBootstrapScript returns the shell script run as the task's main process.
+// It prepares the shared workspace directory, installs or checks the tools DevPod
+// needs depending on the bootstrap mode, aggregates rendered secret templates into
+// <workspace>/.vault-secrets, copies that file into every workspace content
+// directory and then runs the command given as its arguments, or keeps the
+// container running without one.
+func buildBootstrapScript(options *opts.Options, workspacePath string) string {
+	persistent := options.StorageMode == opts.StorageModePersistent
+	nestedDocker := workspaceDriver(options).nestedDocker()
+	secretsFile := workspacePath + "/.vault-secrets"
+
+	// DevPod keeps workspace content in its agent data directory
+	agentDataPath := options.AgentDataPath
+	if agentDataPath == "" {
+		agentDataPath = workspacePath + "/agent"
+	}
+
+	var b strings.Builder
+
+	if persistent {
+		b.WriteString("mkdir -p " + workspacePath + " " + persistentMountPath + "\n\n")
+	} else {
+		b.WriteString("mkdir -p " + workspacePath + "\n\n")
+	}
+	writeToolSetup(&b, options)
+	if persistent {
+		b.WriteString(`# Restore from persistent storage if it has data
+if [ -d ` + persistentMountPath + `/agent ] && [ "$(ls -A ` + persistentMountPath + `/agent 2>/dev/null)" ]; then
+  echo "Restoring workspace from persistent storage..."
+  rsync -a ` + persistentMountPath + `/ ` + workspacePath + `/
+fi
+
+`)
+	}
+
+	if nestedDocker {
+		// DevPod starts devcontainers with the docker CLI, which finds this daemon at
+		// the default socket. Wait for it before marking the workspace as ready, and
+		// fail with its log if it never comes up rather than with a socket error later.
+		b.WriteString(`# Start the workspace's own Docker daemon for devcontainers
+dockerd > /var/log/dockerd.log 2>&1 &
+dockerd_pid=$!
+for i in $(seq 1 60); do
+  docker info > /dev/null 2>&1 && break
+  sleep 1
+done
+if ! docker info > /dev/null 2>&1; then
+  echo "Docker daemon did not start, last lines of /var/log/dockerd.log:" >&2
+  tail -n 20 /var/log/dockerd.log >&2
+  kill "$dockerd_pid" 2>/dev/null
+  exit 1
+fi
+
+`)
+	}
+
+	// Secrets files are written to a temporary file and renamed so readers never
+	// observe a partially written file, and only replaced when the content changed.
+	b.WriteString(`# Combine rendered secret templates into the shared secrets file
+refresh_secrets() {
+  found=""
+  for f in ` + secretsTemplateFiles + `; do
+    [ -f "$f" ] || continue
+    [ -n "$found" ] || : > ` + secretsFile + `.tmp
+    cat "$f" >> ` + secretsFile + `.tmp
+    found=1
+  done
+  [ -n "$found" ] || return 0
+  chmod 644 ` + secretsFile + `.tmp
+  if cmp -s ` + secretsFile + `.tmp ` + secretsFile + `; then
+    rm -f ` + secretsFile + `.tmp
+  else
+    mv -f ` + secretsFile + `.tmp ` + secretsFile + `
+  fi
+}
+
+# Copy the shared secrets file into every workspace content directory
+sync_workspace_secrets() {
+  [ -f ` + secretsFile + ` ] || return 0
+  find ` + agentDataPath + `/contexts/*/workspaces/*/content -maxdepth 0 -type d 2>/dev/null | while read wsdir; do
+    if ! cmp -s ` + secretsFile + ` "$wsdir/.vault-secrets"; then
+      cp ` + secretsFile + ` "$wsdir/.vault-secrets.tmp" && chmod 644 "$wsdir/.vault-secrets.tmp" && mv -f "$wsdir/.vault-secrets.tmp" "$wsdir/.vault-secrets"
+    fi
+  done
+}
+
+`)
+
+	// A shell only runs its EXIT trap when it exits normally, not when it is killed
+	// by a signal, so the final sync runs from a TERM and INT handler. It is set
+	// after the restore so stopping a starting workspace can't overwrite persistent
+	// storage with an incomplete copy, and before the workspace is marked ready so
+	// a stop right after that is never missed.
+	b.WriteString(`# Stop the workspace command and background processes`)
+	if nestedDocker {
+		b.WriteString(` and the Docker daemon`)
+	}
+	if persistent {
+		b.WriteString(`, then flush the workspace to persistent storage,`)
+	}
+	b.WriteString(` when Nomad stops the task
+shutdown() {
+  trap '' TERM INT
+  kill "$command_pid" 2>/dev/null
+  wait "$command_pid" 2>/dev/null
+  kill $bg_pids 2>/dev/null
+  wait $bg_pids 2>/dev/null
+`)
+	if nestedDocker {
+		b.WriteString(`  echo "Stopping Docker daemon..."
+  kill "$dockerd_pid" 2>/dev/null
+  wait "$dockerd_pid" 2>/dev/null
+`)
+	}
+	if persistent {
+		b.WriteString(`  echo "Syncing to persistent storage..."
+  rsync -a --delete ` + workspacePath + `/ ` + persistentMountPath + `/
+`)
+	}
+	b.WriteString(`  exit "${1:-0}"
+}
+trap shutdown TERM INT
+
+`)
+
+	b.WriteString(`refresh_secrets
+
+# Mark as ready
+sleep 2 && touch ` + readyMarkerPath + `
+
+`)
+
+	// Background loops wait on a background sleep so the shutdown handler can stop
+	// them without waiting for the sleep, but never interrupts a command in progress
+	if liveSecretRotation(options) {
+		b.WriteString(`# Background process: pick up rotated secrets and copy them to workspace content directories
+(trap 'kill $sleep_pid 2>/dev/null; exit 0' TERM
+while true; do
+  refresh_secrets
+  sync_workspace_secrets
+  sleep 5 & sleep_pid=$!; wait $sleep_pid
+done) &
+bg_pids="$bg_pids $!"
+
+# Refresh immediately when Nomad signals a template change
+trap 'refresh_secrets; sync_workspace_secrets' ` + strings.Join(secretRefreshSignals(options), " ") + `
+
+`)
+	} else {
+		b.WriteString(`# Background process: copy secrets to workspace content directories
+(trap 'kill $sleep_pid 2>/dev/null; exit 0' TERM
+while true; do
+  sync_workspace_secrets
+  sleep 5 & sleep_pid=$!; wait $sleep_pid
+done) &
+bg_pids="$bg_pids $!"
+
+`)
+	}
+
+	if persistent {
+		b.WriteString(`# Background process: sync to persistent storage every 60 seconds
+(trap 'kill $sleep_pid 2>/dev/null; exit 0' TERM
+while true; do
+  sleep 60 & sleep_pid=$!; wait $sleep_pid
+  rsync -a --delete ` + workspacePath + `/ ` + persistentMountPath + `/ 2>/dev/null || true
+done) &
+bg_pids="$bg_pids $!"
+
+`)
+	}
+
+	// The devcontainer's command is passed as the script's arguments. It runs in the
+	// background so the shell can run its traps while waiting, and the task exits
+	// with its status once it ends.
+	b.WriteString(`# Run the workspace command, or keep the container running without one
+[ "$#" -gt 0 ] || set -- sleep infinity
+`)
+	// Only the command drops to the devcontainer's user, the setup above needs root
+	if user := commandUser(options); user != "" {
+		b.WriteString("# Run it as the devcontainer's user\n")
+		if name, group, ok := strings.Cut(user, ":"); ok {
+			b.WriteString("set -- setpriv --reuid=" + shellQuote(name) + " --regid=" + shellQuote(group) + " --clear-groups -- \"$@\"\n")
+		} else {
+			b.WriteString("set -- runuser -u " + shellQuote(user) + " -- \"$@\"\n")
+		}
+	}
+	b.WriteString(`"$@" &
+command_pid=$!
+while kill -0 $command_pid 2>/dev/null; do wait $command_pid; status=$?; done
+shutdown "$status"
+`)
+
+	return b.String()
+}
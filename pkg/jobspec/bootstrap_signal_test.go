@@ -0,0 +1,321 @@
+//go:build linux
+
+package jobspec
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+	"github.com/loft-sh/devpod/pkg/driver"
+)
+
+// bootstrapRun is the bootstrap script running locally with stub commands
+type bootstrapRun struct {
+	cmd       *exec.Cmd
+	done      chan error
+	workspace string
+	events    string
+}
+
+// startBootstrap runs the bootstrap script outside a container. The commands it
+// installs or can't run unprivileged are replaced by stubs that append to an events
+// log, and the persistent mount and ready marker are moved into a temporary directory.
+func startBootstrap(t *testing.T, options *opts.Options, stubs map[string]string) *bootstrapRun {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("runs the bootstrap script")
+	}
+
+	dir := t.TempDir()
+	run := &bootstrapRun{
+		done:      make(chan error, 1),
+		workspace: filepath.Join(dir, "workspace"),
+		events:    filepath.Join(dir, "events.log"),
+	}
+
+	commands := map[string]string{
+		"apt-get":                `exit 0`,
+		"update-ca-certificates": `exit 0`,
+		"rsync":                  `echo "rsync $*" >> ` + run.events,
+		"docker":                 `exit 0`,
+		"dockerd":                `trap 'echo "dockerd stopped" >> ` + run.events + `; exit 0' TERM; sleep 600 & wait $!`,
+	}
+	for name, body := range stubs {
+		commands[name] = body
+	}
+	bin := filepath.Join(dir, "bin")
+	if err := os.Mkdir(bin, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, body := range commands {
+		if err := os.WriteFile(filepath.Join(bin, name), []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	script := buildBootstrapScript(options, run.workspace)
+	script = strings.ReplaceAll(script, persistentMountPath, filepath.Join(dir, "persistent"))
+	script = strings.ReplaceAll(script, readyMarkerPath, filepath.Join(dir, "ready"))
+	script = strings.ReplaceAll(script, "/var/log/dockerd.log", filepath.Join(dir, "dockerd.log"))
+
+	args := []string{"-c", script}
+	if command := runCommand(options); len(command) > 0 {
+		args = append(append(args, "devpod-bootstrap"), command...)
+	}
+	run.cmd = exec.Command("/bin/sh", args...)
+	run.cmd.Env = append(os.Environ(), "PATH="+bin+":"+os.Getenv("PATH"))
+	// A process group of its own lets the cleanup kill whatever the script left behind
+	run.cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := run.cmd.Start(); err != nil {
+		t.Fatalf("Failed to start bootstrap script: %v", err)
+	}
+	go func() { run.done <- run.cmd.Wait() }()
+	t.Cleanup(func() {
+		_ = syscall.Kill(-run.cmd.Process.Pid, syscall.SIGKILL)
+	})
+
+	return run
+}
+
+// waitForFile waits until path exists
+func waitForFile(t *testing.T, path string) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(path); err == nil {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for %s", path)
+}
+
+// stop sends sig to the script and returns its exit error
+func (r *bootstrapRun) stop(t *testing.T, sig syscall.Signal) error {
+	t.Helper()
+	if err := r.cmd.Process.Signal(sig); err != nil {
+		t.Fatalf("Failed to signal bootstrap script: %v", err)
+	}
+	select {
+	case err := <-r.done:
+		return err
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Bootstrap script did not exit after %v", sig)
+		return nil
+	}
+}
+
+func (r *bootstrapRun) readEvents(t *testing.T) []string {
+	t.Helper()
+	data, err := os.ReadFile(r.events)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestBootstrapScript_SignalFlushesPersistentData(t *testing.T) {
+	for _, sig := range []syscall.Signal{syscall.SIGTERM, syscall.SIGINT} {
+		t.Run(sig.String(), func(t *testing.T) {
+			t.Parallel()
+			run := startBootstrap(t, &opts.Options{StorageMode: opts.StorageModePersistent}, nil)
+			waitForFile(t, filepath.Join(filepath.Dir(run.events), "ready"))
+
+			if err := run.stop(t, sig); err != nil {
+				t.Fatalf("Expected a clean exit, got: %v", err)
+			}
+			events := run.readEvents(t)
+			if len(events) != 1 || !strings.HasPrefix(events[0], "rsync -a --delete "+run.workspace+"/ ") {
+				t.Errorf("Expected one final sync, got %q", events)
+			}
+		})
+	}
+}
+
+func TestBootstrapScript_SignalStopsDockerBeforeSync(t *testing.T) {
+	t.Parallel()
+	options := &opts.Options{StorageMode: opts.StorageModePersistent, TaskDriver: opts.TaskDriverSysbox}
+	run := startBootstrap(t, options, nil)
+	waitForFile(t, filepath.Join(filepath.Dir(run.events), "ready"))
+
+	if err := run.stop(t, syscall.SIGTERM); err != nil {
+		t.Fatalf("Expected a clean exit, got: %v", err)
+	}
+	events := run.readEvents(t)
+	if len(events) != 2 || events[0] != "dockerd stopped" || !strings.HasPrefix(events[1], "rsync ") {
+		t.Errorf("Expected dockerd to stop before the final sync, got %q", events)
+	}
+}
+
+func TestBootstrapScript_SignalWaitsForRunningSync(t *testing.T) {
+	t.Parallel()
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep not found")
+	}
+	dir := t.TempDir()
+	syncs := filepath.Join(dir, "syncs.log")
+	// Periodic syncs run back to back and take a while, so the signal arrives mid-sync
+	stubs := map[string]string{
+		"sleep": `[ "$1" = 60 ] && exit 0; exec ` + sleep + ` "$@"`,
+		"rsync": `echo start >> ` + syncs + `; ` + sleep + ` 0.3; echo end >> ` + syncs,
+	}
+	run := startBootstrap(t, &opts.Options{StorageMode: opts.StorageModePersistent}, stubs)
+	waitForFile(t, syncs)
+
+	if err := run.stop(t, syscall.SIGTERM); err != nil {
+		t.Fatalf("Expected a clean exit, got: %v", err)
+	}
+	data, err := os.ReadFile(syncs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every sync ends before the next one starts, and the final sync completes
+	if strings.Contains(string(data), "start\nstart") || !strings.HasSuffix(string(data), "start\nend\n") {
+		t.Errorf("Expected syncs not to overlap, got:\n%s", data)
+	}
+}
+
+func TestBootstrapScript_SignalDuringStartupDoesNotSync(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	installing := filepath.Join(dir, "installing")
+	// Stopped while installing packages, before the workspace has been restored
+	stubs := map[string]string{
+		"apt-get": `touch ` + installing + `; sleep 600`,
+	}
+	run := startBootstrap(t, &opts.Options{StorageMode: opts.StorageModePersistent}, stubs)
+	waitForFile(t, installing)
+
+	if err := run.stop(t, syscall.SIGTERM); err == nil {
+		t.Error("Expected the script to be killed by the signal")
+	}
+	if events := run.readEvents(t); len(events) != 0 {
+		t.Errorf("Expected no sync before the workspace was restored, got %q", events)
+	}
+}
+
+func TestBootstrapScript_SignalStopsEphemeralWorkspace(t *testing.T) {
+	t.Parallel()
+	run := startBootstrap(t, &opts.Options{StorageMode: opts.StorageModeEphemeral}, nil)
+	waitForFile(t, filepath.Join(filepath.Dir(run.events), "ready"))
+
+	if err := run.stop(t, syscall.SIGTERM); err != nil {
+		t.Fatalf("Expected a clean exit, got: %v", err)
+	}
+	if events := run.readEvents(t); len(events) != 0 {
+		t.Errorf("Expected no sync in ephemeral mode, got %q", events)
+	}
+}
+
+func TestBootstrapScript_CommandExitEndsTask(t *testing.T) {
+	t.Parallel()
+	options := &opts.Options{
+		StorageMode: opts.StorageModePersistent,
+		DriverOpts:  &driver.RunOptions{Entrypoint: "/bin/sh", Cmd: []string{"-c", "exit 3"}},
+	}
+	run := startBootstrap(t, options, nil)
+
+	var err error
+	select {
+	case err = <-run.done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Bootstrap script did not exit with the workspace command")
+	}
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 3 {
+		t.Errorf("Expected the command's exit status 3, got: %v", err)
+	}
+	events := run.readEvents(t)
+	if len(events) != 1 || !strings.HasPrefix(events[0], "rsync -a --delete ") {
+		t.Errorf("Expected one final sync, got %q", events)
+	}
+}
+
+func TestBootstrapScript_SignalStopsCommand(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	stopped := filepath.Join(dir, "stopped")
+	command := `trap 'touch ` + stopped + `; exit 0' TERM; sleep 600 & wait $!`
+	options := &opts.Options{
+		StorageMode: opts.StorageModeEphemeral,
+		DriverOpts:  &driver.RunOptions{Cmd: []string{"/bin/sh", "-c", command}},
+	}
+	run := startBootstrap(t, options, nil)
+	waitForFile(t, filepath.Join(filepath.Dir(run.events), "ready"))
+
+	if err := run.stop(t, syscall.SIGTERM); err != nil {
+		t.Fatalf("Expected a clean exit, got: %v", err)
+	}
+	if _, err := os.Stat(stopped); err != nil {
+		t.Error("Expected the workspace command to receive SIGTERM")
+	}
+}
+
+func TestBootstrapScript_DockerDaemonFailureStopsBootstrap(t *testing.T) {
+	options := &opts.Options{StorageMode: opts.StorageModeEphemeral, TaskDriver: opts.TaskDriverSysbox}
+	run := startBootstrap(t, options, map[string]string{
+		"dockerd": `echo "failed to start daemon: operation not permitted"; exit 1`,
+		"docker":  `exit 1`,
+		// Give up on the daemon after one attempt
+		"seq": `echo 1`,
+	})
+
+	var err error
+	select {
+	case err = <-run.done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Bootstrap script did not exit without a Docker daemon")
+	}
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+		t.Fatalf("Expected exit status 1, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(run.workspace), "ready")); err == nil {
+		t.Error("Expected the workspace not to be marked ready")
+	}
+}
+
+func TestBootstrapScript_SetupRunsAsRootAndCommandAsUser(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	events := filepath.Join(dir, "users.log")
+	options := &opts.Options{
+		StorageMode: opts.StorageModeEphemeral,
+		DriverOpts: &driver.RunOptions{
+			User: "vscode",
+			Cmd:  []string{"/bin/sh", "-c", `echo "command as ${RUN_AS:-root}" >> ` + events},
+		},
+	}
+	run := startBootstrap(t, options, map[string]string{
+		// The stubs log the user they would run as, which is the user of the script
+		// unless the script switched users for them
+		"apt-get":                `echo "apt-get as ${RUN_AS:-root}" >> ` + events,
+		"update-ca-certificates": `echo "update-ca-certificates as ${RUN_AS:-root}" >> ` + events,
+		"runuser":                `[ "$1" = -u ] && [ "$3" = -- ] || exit 1; user=$2; shift 3; RUN_AS=$user exec "$@"`,
+	})
+
+	select {
+	case err := <-run.done:
+		if err != nil {
+			t.Fatalf("Expected a clean exit, got: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Bootstrap script did not exit with the workspace command")
+	}
+	data, err := os.ReadFile(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "apt-get as root\napt-get as root\nupdate-ca-certificates as root\ncommand as vscode\n"
+	if string(data) != want {
+		t.Errorf("Expected the setup as root and only the command as vscode, got:\n%s", data)
+	}
+}
@@ -0,0 +1,237 @@
+package jobspec
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+	"github.com/loft-sh/devpod/pkg/driver"
+)
+
+func TestBuildBootstrapScript_ValidShellSyntax(t *testing.T) {
+	for _, storageMode := range []string{opts.StorageModeEphemeral, opts.StorageModePersistent} {
+		for _, changeMode := range []string{"restart", "noop", "signal"} {
+			options := &opts.Options{
+				StorageMode:     storageMode,
+				VaultChangeMode: changeMode,
+				VaultSecrets:    []opts.VaultSecret{{Path: "secret/data/test", Fields: map[string]string{"key": "KEY"}}},
+			}
+
+			script := buildBootstrapScript(options, "/tmp/devpod-workspaces")
+
+			out, err := exec.Command("/bin/sh", "-n", "-c", script).CombinedOutput()
+			if err != nil {
+				t.Errorf("Invalid shell syntax for storage=%s change_mode=%s: %v\n%s", storageMode, changeMode, err, out)
+			}
+		}
+	}
+}
+
+func TestBuildBootstrapScript_NestedDocker(t *testing.T) {
+	for _, storageMode := range []string{opts.StorageModeEphemeral, opts.StorageModePersistent} {
+		options := &opts.Options{
+			StorageMode: storageMode,
+			TaskDriver:  opts.TaskDriverSysbox,
+		}
+
+		script := buildBootstrapScript(options, "/tmp/devpod-workspaces")
+
+		if out, err := exec.Command("/bin/sh", "-n", "-c", script).CombinedOutput(); err != nil {
+			t.Errorf("Invalid shell syntax for storage=%s: %v\n%s", storageMode, err, out)
+		}
+		if !strings.Contains(script, " docker.io") || !strings.Contains(script, "dockerd > /var/log/dockerd.log") {
+			t.Errorf("Expected Docker to be installed and started for storage=%s", storageMode)
+		}
+		// devcontainers can only start once the daemon is up
+		if strings.Index(script, "docker info") > strings.Index(script, "touch "+readyMarkerPath) {
+			t.Error("Expected the ready marker after waiting for dockerd")
+		}
+	}
+
+	options := &opts.Options{StorageMode: opts.StorageModeEphemeral, TaskDriver: opts.TaskDriverDocker}
+	if strings.Contains(buildBootstrapScript(options, "/tmp/devpod-workspaces"), "dockerd") {
+		t.Error("Expected no nested Docker daemon with the docker driver")
+	}
+}
+
+func TestBuildBootstrapScript_RestartModeDoesNotWatchSecrets(t *testing.T) {
+	options := &opts.Options{
+		StorageMode:     opts.StorageModeEphemeral,
+		VaultChangeMode: "restart",
+		VaultSecrets:    []opts.VaultSecret{{Path: "secret/data/test", Fields: map[string]string{"key": "KEY"}}},
+	}
+
+	script := buildBootstrapScript(options, "/tmp/devpod-workspaces")
+
+	if strings.Contains(script, "trap 'refresh_secrets") {
+		t.Error("Expected no refresh trap in restart mode")
+	}
+	if !strings.Contains(script, "sync_workspace_secrets") {
+		t.Error("Expected workspace secrets to be copied in restart mode")
+	}
+}
+
+func TestBuildBootstrapScript_LiveRotationModesWatchSecrets(t *testing.T) {
+	for _, changeMode := range []string{"noop", "signal"} {
+		options := &opts.Options{
+			StorageMode:     opts.StorageModeEphemeral,
+			VaultChangeMode: changeMode,
+			VaultSecrets:    []opts.VaultSecret{{Path: "secret/data/test", Fields: map[string]string{"key": "KEY"}}},
+		}
+
+		script := buildBootstrapScript(options, "/tmp/devpod-workspaces")
+
+		if !strings.Contains(script, "trap 'refresh_secrets; sync_workspace_secrets' HUP") {
+			t.Errorf("Expected HUP refresh trap for change mode %s", changeMode)
+		}
+		if strings.Count(script, "  refresh_secrets\n") != 1 {
+			t.Errorf("Expected refresh_secrets in the background loop for change mode %s", changeMode)
+		}
+	}
+}
+
+func TestBuildBootstrapScript_UsesWorkspacePath(t *testing.T) {
+	options := &opts.Options{
+		StorageMode: opts.StorageModePersistent,
+	}
+
+	script := buildBootstrapScript(options, "/srv/workspaces")
+
+	if strings.Contains(script, "/tmp/devpod-workspaces") {
+		t.Error("Expected script to only reference the given workspace path")
+	}
+	if !strings.Contains(script, "rsync -a --delete /srv/workspaces/ /persistent/") {
+		t.Error("Expected persistent sync of the given workspace path")
+	}
+}
+
+func TestBuildBootstrapScript_TrapsPerSecretSignals(t *testing.T) {
+	options := &opts.Options{
+		StorageMode:       opts.StorageModeEphemeral,
+		VaultChangeMode:   "restart",
+		VaultChangeSignal: "SIGHUP",
+		VaultSecrets: []opts.VaultSecret{
+			{Path: "secret/data/db", Fields: map[string]string{"password": "DB_PASSWORD"}},
+			{Path: "secret/data/api", Fields: map[string]string{"key": "API_KEY"}, ChangeMode: "signal", ChangeSignal: "SIGUSR1"},
+		},
+	}
+
+	script := buildBootstrapScript(options, "/tmp/devpod-workspaces")
+
+	if !strings.Contains(script, "trap 'refresh_secrets; sync_workspace_secrets' HUP USR1\n") {
+		t.Error("Expected refresh trap for HUP and the per-secret USR1 signal")
+	}
+}
+
+func TestBuildBootstrapScript_RestartForAllSecretsDoesNotWatch(t *testing.T) {
+	options := &opts.Options{
+		StorageMode:     opts.StorageModeEphemeral,
+		VaultChangeMode: "noop",
+		VaultSecrets: []opts.VaultSecret{
+			{Path: "secret/data/db", Fields: map[string]string{"password": "DB_PASSWORD"}, ChangeMode: "restart"},
+		},
+	}
+
+	if liveSecretRotation(options) {
+		t.Error("Expected no live rotation when every secret restarts the task")
+	}
+}
+
+func TestBuildBootstrapScript_BootstrapModes(t *testing.T) {
+	for _, mode := range []string{opts.BootstrapModeInstall, opts.BootstrapModePrebuilt, opts.BootstrapModeAuto} {
+		options := &opts.Options{StorageMode: opts.StorageModePersistent, TaskDriver: opts.TaskDriverSysbox, BootstrapMode: mode}
+
+		script := buildBootstrapScript(options, "/tmp/devpod-workspaces")
+
+		if out, err := exec.Command("/bin/sh", "-n", "-c", script).CombinedOutput(); err != nil {
+			t.Errorf("Invalid shell syntax for mode=%s: %v\n%s", mode, err, out)
+		}
+		if mode == opts.BootstrapModePrebuilt && strings.Contains(script, "apt-get") {
+			t.Error("Expected no apt-get in prebuilt mode")
+		}
+		if mode != opts.BootstrapModeInstall && !strings.Contains(script, "command -v rsync") {
+			t.Errorf("Expected the tools to be checked in mode=%s", mode)
+		}
+		// The image's tools must be in place before the workspace is restored
+		if strings.Index(script, "update-ca-certificates") > strings.Index(script, "rsync -a "+persistentMountPath) {
+			t.Errorf("Expected the tools before the restore in mode=%s", mode)
+		}
+	}
+}
+
+func TestBuildBootstrapScript_PrebuiltFailsWithoutTools(t *testing.T) {
+	options := &opts.Options{StorageMode: opts.StorageModeEphemeral, BootstrapMode: opts.BootstrapModePrebuilt}
+	dir := t.TempDir()
+
+	cmd := exec.Command("/bin/sh", "-c", buildBootstrapScript(options, dir))
+	// Without curl, git and update-ca-certificates on the PATH
+	cmd.Env = []string{"PATH=" + t.TempDir()}
+	out, err := cmd.CombinedOutput()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+		t.Fatalf("Expected exit status 1, got %v", err)
+	}
+	if !strings.Contains(string(out), "missing required tools: curl git update-ca-certificates") {
+		t.Errorf("Expected the missing tools in the output, got %q", out)
+	}
+}
+
+func TestBuildBootstrapScript_FailedInstallStopsBootstrap(t *testing.T) {
+	// apt-get fails and no other tool is on the PATH
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "apt-get"), []byte("#!/bin/sh\nexit 100\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mode := range []string{opts.BootstrapModeInstall, opts.BootstrapModeAuto} {
+		options := &opts.Options{StorageMode: opts.StorageModeEphemeral, BootstrapMode: mode}
+
+		cmd := exec.Command("/bin/sh", "-c", buildBootstrapScript(options, t.TempDir()))
+		cmd.Env = []string{"PATH=" + bin}
+		out, err := cmd.CombinedOutput()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+			t.Errorf("Expected exit status 1 before the ready marker for mode=%s, got %v\n%s", mode, err, out)
+		}
+	}
+}
+
+func TestBuildBootstrapScript_CommandUser(t *testing.T) {
+	tests := []struct {
+		user string
+		want string
+	}{
+		{"", ""},
+		{"root", ""},
+		{"0:0", ""},
+		{"vscode", `set -- runuser -u 'vscode' -- "$@"`},
+		{"1000:1000", `set -- setpriv --reuid='1000' --regid='1000' --clear-groups -- "$@"`},
+	}
+	for _, tt := range tests {
+		options := &opts.Options{
+			StorageMode:   opts.StorageModeEphemeral,
+			BootstrapMode: opts.BootstrapModePrebuilt,
+			DriverOpts:    &driver.RunOptions{User: tt.user},
+		}
+		script := buildBootstrapScript(options, "/tmp/devpod-workspaces")
+
+		if out, err := exec.Command("/bin/sh", "-n", "-c", script).CombinedOutput(); err != nil {
+			t.Errorf("Invalid shell syntax for user %q: %v\n%s", tt.user, err, out)
+		}
+		if tt.want == "" {
+			if strings.Contains(script, "runuser") || strings.Contains(script, "setpriv") {
+				t.Errorf("Expected no user switch for user %q", tt.user)
+			}
+			continue
+		}
+		// Only the command switches users, after the setup that needs root
+		if strings.Count(script, tt.want) != 1 || strings.Index(script, tt.want) < strings.Index(script, "touch "+readyMarkerPath) {
+			t.Errorf("Expected the command to run with %q after the setup for user %q:\n%s", tt.want, tt.user, script)
+		}
+		command, _, _ := strings.Cut(tt.want[len("set -- "):], " ")
+		if !strings.Contains(script, "command -v "+command+" ") {
+			t.Errorf("Expected the prebuilt image to be checked for %s", command)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package jobspec
+
+import (
+	"path"
+
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+	"github.com/hashicorp/nomad/api"
+)
+
+// CleanupTaskName is the name of the task removing the workspace directory
+const CleanupTaskName = "cleanup"
+
+// BuildCleanupJob returns a batch job that removes the workspace directory from
+// node, the Nomad client the workspace ran on. The directory is bind-mounted from
+// the client, which the provider can't reach directly, so the job mounts its
+// parent and removes it with the workspace image, already present on the node.
+func BuildCleanupJob(options *opts.Options, jobID string, node *api.Node) *api.Job {
+	root := path.Dir(options.WorkspacePath())
+	image := workspaceImage(options)
+
+	task := &api.Task{
+		Name:   CleanupTaskName,
+		Driver: "docker",
+		User:   defaultUser,
+		Config: map[string]interface{}{
+			"image":   image,
+			"command": "rm",
+			"args":    []string{"-rf", options.WorkspacePath()},
+			"volumes": []string{root + ":" + root},
+		},
+	}
+	configureImagePull(task, image, jobID, options)
+
+	job := buildBatchJob(options, jobID, task)
+	job.Constraints = []*api.Constraint{api.NewConstraint("${node.unique.id}", "=", node.ID)}
+	job.Datacenters = []string{node.Datacenter}
+	if node.NodePool != "" {
+		job.NodePool = &node.NodePool
+	}
+	return job
+}
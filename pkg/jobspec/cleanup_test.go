@@ -0,0 +1,46 @@
+package jobspec
+
+import (
+	"strings"
+	"testing"
+
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+	"github.com/hashicorp/nomad/api"
+)
+
+func TestBuildCleanupJob(t *testing.T) {
+	options := baseOptions()
+	options.StorageMode = opts.StorageModePersistent
+	options.NodePool = "workspaces"
+	options.Image = "registry.example.com/devpod/base:1"
+	node := &api.Node{ID: "0b6a4f5e-node", Name: "client-1", Datacenter: "dc2", NodePool: "gpu"}
+
+	job := BuildCleanupJob(options, "devpod-test-cleanup-1", node)
+
+	if *job.Type != api.JobTypeBatch || *job.TaskGroups[0].RestartPolicy.Attempts != 0 {
+		t.Errorf("Expected a batch job that isn't retried, got %s", *job.Type)
+	}
+	// Pinned to the workspace's node, wherever the workspace placement points now
+	if len(job.Constraints) != 1 || job.Constraints[0].LTarget != "${node.unique.id}" || job.Constraints[0].RTarget != node.ID {
+		t.Errorf("Expected the job pinned to the node, got %v", job.Constraints)
+	}
+	if *job.NodePool != "gpu" || len(job.Datacenters) != 1 || job.Datacenters[0] != "dc2" {
+		t.Errorf("Expected the node's pool and datacenter, got %v %v", *job.NodePool, job.Datacenters)
+	}
+
+	task := job.TaskGroups[0].Tasks[0]
+	if task.Name != CleanupTaskName || task.Driver != "docker" || task.Config["image"] != options.Image {
+		t.Errorf("Expected the workspace image on docker, got %s %s %v", task.Name, task.Driver, task.Config["image"])
+	}
+	args := strings.Join(task.Config["args"].([]string), " ")
+	if task.Config["command"] != "rm" || args != "-rf /tmp/devpod-workspaces/devpod-test" {
+		t.Errorf("Expected the whole workspace directory removed, got %v %s", task.Config["command"], args)
+	}
+	volumes := task.Config["volumes"].([]string)
+	if len(volumes) != 1 || volumes[0] != "/tmp/devpod-workspaces:/tmp/devpod-workspaces" {
+		t.Errorf("Expected the parent directory mounted, got %v", volumes)
+	}
+	if len(job.TaskGroups[0].Volumes) != 0 {
+		t.Error("Expected no CSI volume in the cleanup job")
+	}
+}
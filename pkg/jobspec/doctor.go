@@ -0,0 +1,79 @@
+package jobspec
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+	"github.com/hashicorp/nomad/api"
+)
+
+// Prefix of the line the doctor check prints the missing commands on
+const doctorMissingPrefix = "devpod-missing:"
+
+// DoctorTool is a command the workspace image was checked for
+type DoctorTool struct {
+	Command string // Command the bootstrap script or DevPod runs
+	Package string // Debian package providing it
+	Missing bool   // Not found in the image
+}
+
+// DoctorImage returns the image the doctor check runs by default, the one the
+// workspace would use
+func DoctorImage(options *opts.Options) string {
+	return workspaceImage(options)
+}
+
+// BuildDoctorJob returns a batch job that runs image with the workspace's task
+// driver, placement and registry credentials, and prints which of the tools the
+// workspace needs are missing from it
+func BuildDoctorJob(options *opts.Options, jobID string, image string) *api.Job {
+	var script strings.Builder
+	writeToolChecks(&script, bootstrapTools(options))
+	script.WriteString(`echo "` + doctorMissingPrefix + `$missing"` + "\n")
+
+	taskDriver := workspaceDriver(options)
+	config := taskDriver.config(taskDriver.image(image), []string{"/bin/sh", "-c", script.String()}, nil)
+	delete(config, "volumes")
+
+	task := &api.Task{
+		Name:   options.TaskName,
+		Driver: taskDriver.name(),
+		Config: config,
+	}
+	configureImagePull(task, image, jobID, options)
+
+	job := buildBatchJob(options, jobID, task)
+	job.Constraints = buildJobConstraints(options)
+	if len(options.Datacenters) > 0 {
+		job.Datacenters = options.Datacenters
+	}
+	if options.NodePool != "" {
+		job.NodePool = &options.NodePool
+	}
+	return job
+}
+
+// ParseDoctorOutput returns the tools the workspace needs and whether the doctor
+// check found them, from the output of the doctor job's task
+func ParseDoctorOutput(options *opts.Options, output string) ([]DoctorTool, error) {
+	for _, line := range strings.Split(output, "\n") {
+		missingList, ok := strings.CutPrefix(strings.TrimSpace(line), doctorMissingPrefix)
+		if !ok {
+			continue
+		}
+
+		missing := strings.Fields(missingList)
+		var tools []DoctorTool
+		for _, tool := range bootstrapTools(options) {
+			tools = append(tools, DoctorTool{
+				Command: tool.command,
+				Package: tool.pkg,
+				Missing: slices.Contains(missing, tool.command),
+			})
+		}
+		return tools, nil
+	}
+	return nil, fmt.Errorf("doctor check printed no result")
+}
@@ -0,0 +1,85 @@
+package jobspec
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+	"github.com/hashicorp/nomad/api"
+)
+
+func TestBuildDoctorJob(t *testing.T) {
+	options := baseOptions()
+	options.TaskDriver = opts.TaskDriverPodman
+	options.NodePool = "workspaces"
+	options.RegistryAuth = []opts.RegistryAuth{{Registry: "ghcr.io", Token: "ghp_example"}}
+
+	job := BuildDoctorJob(options, "devpod-test-doctor-1", "ghcr.io/acme/dev:latest")
+
+	if *job.ID != "devpod-test-doctor-1" || *job.Type != api.JobTypeBatch || *job.NodePool != "workspaces" {
+		t.Errorf("Expected batch job in the workspace node pool, got %s %s %v", *job.ID, *job.Type, job.NodePool)
+	}
+	group := job.TaskGroups[0]
+	if *group.RestartPolicy.Attempts != 0 || *group.ReschedulePolicy.Attempts != 0 {
+		t.Error("Expected the check not to be retried")
+	}
+	task := group.Tasks[0]
+	if task.Driver != "podman" || task.Config["image"] != "docker://ghcr.io/acme/dev:latest" {
+		t.Errorf("Expected the workspace task driver, got %s %v", task.Driver, task.Config["image"])
+	}
+	if _, ok := task.Config["volumes"]; ok {
+		t.Error("Expected no volumes in the check")
+	}
+	if _, ok := task.Config["auth"]; !ok || len(task.Templates) != 1 || !strings.Contains(*task.Templates[0].EmbeddedTmpl, "nomad/jobs/devpod-test-doctor-1") {
+		t.Error("Expected the registry credentials of the image from the check job's variable")
+	}
+}
+
+func TestDoctorCheck_ReportsMissingTools(t *testing.T) {
+	// Only some tools exist on the PATH the check runs with
+	bin := t.TempDir()
+	for _, name := range []string{"curl", "update-ca-certificates", "docker"} {
+		if err := os.WriteFile(filepath.Join(bin, name), []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	options := &opts.Options{StorageMode: opts.StorageModePersistent, TaskDriver: opts.TaskDriverSysbox}
+	job := BuildDoctorJob(options, "devpod-test-doctor-1", "ubuntu:22.04")
+	script := job.TaskGroups[0].Tasks[0].Config["args"].([]string)[2]
+
+	cmd := exec.Command("/bin/sh", "-c", script)
+	cmd.Env = []string{"PATH=" + bin}
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Doctor check failed: %v", err)
+	}
+
+	tools, err := ParseDoctorOutput(options, string(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var missing []string
+	for _, tool := range tools {
+		if tool.Missing {
+			missing = append(missing, tool.Command+"="+tool.Package)
+		}
+	}
+	want := []string{"git=git", "dockerd=docker.io", "rsync=rsync"}
+	if len(tools) != 6 || len(missing) != len(want) {
+		t.Fatalf("Expected missing %v, got %v", want, missing)
+	}
+	for i := range want {
+		if missing[i] != want[i] {
+			t.Errorf("Expected missing %v, got %v", want, missing)
+		}
+	}
+}
+
+func TestParseDoctorOutput_NoResult(t *testing.T) {
+	if _, err := ParseDoctorOutput(&opts.Options{}, "exec format error\n"); err == nil {
+		t.Error("Expected error without the check result")
+	}
+}
@@ -0,0 +1,112 @@
+package jobspec
+
+import (
+	"strings"
+
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+)
+
+// Path of the client's Docker socket, bind-mounted for the docker driver
+const dockerSocketPath = "/var/run/docker.sock"
+
+// taskDriver builds the workspace task for one Nomad task driver and decides how
+// DevPod gets the Docker daemon it runs devcontainers with
+type taskDriver interface {
+	// name returns the Nomad task driver
+	name() string
+
+	// image returns the image reference in the form the driver expects
+	image(image string) string
+
+	// config returns the driver config of the workspace task. It runs an init process
+	// as PID 1 that forwards Nomad's kill signal to the bootstrap script and reaps
+	// orphaned processes.
+	config(image string, args []string, volumes []string) map[string]interface{}
+
+	// nestedDocker reports whether the workspace runs its own Docker daemon instead
+	// of using the client's through the bind-mounted socket
+	nestedDocker() bool
+}
+
+// workspaceDriver returns the task driver selected in the options, defaulting to docker
+func workspaceDriver(options *opts.Options) taskDriver {
+	switch options.TaskDriver {
+	case opts.TaskDriverPodman:
+		return podmanDriver{}
+	case opts.TaskDriverSysbox:
+		return sysboxDriver{}
+	default:
+		return dockerDriver{}
+	}
+}
+
+// dockerDriver runs the workspace as a privileged Docker container that starts
+// devcontainers as siblings on the client's Docker daemon
+type dockerDriver struct{}
+
+func (dockerDriver) name() string { return "docker" }
+
+func (dockerDriver) image(image string) string { return image }
+
+func (dockerDriver) config(image string, args []string, volumes []string) map[string]interface{} {
+	return map[string]interface{}{
+		"image":        image,
+		"args":         args,
+		"volumes":      volumes,
+		"init":         true,
+		"privileged":   true,
+		"network_mode": "bridge",
+	}
+}
+
+func (dockerDriver) nestedDocker() bool { return false }
+
+// podmanDriver runs the workspace with the Podman driver. With rootless Podman on
+// the client, privileged only grants capabilities inside the user namespace, which
+// is enough for the nested Docker daemon.
+type podmanDriver struct{}
+
+func (podmanDriver) name() string { return "podman" }
+
+// image adds the docker:// transport so unqualified names resolve on Docker Hub
+// instead of failing Podman's short-name enforcement
+func (podmanDriver) image(image string) string {
+	if strings.Contains(image, "://") {
+		return image
+	}
+	return "docker://" + image
+}
+
+func (podmanDriver) config(image string, args []string, volumes []string) map[string]interface{} {
+	return map[string]interface{}{
+		"image":      image,
+		"args":       args,
+		"volumes":    volumes,
+		"init":       true,
+		"privileged": true,
+	}
+}
+
+func (podmanDriver) nestedDocker() bool { return true }
+
+// sysboxDriver runs the workspace with the Docker driver and the Sysbox runtime,
+// which isolates the container in a user namespace and lets it run a Docker daemon
+// without privileged mode
+type sysboxDriver struct{}
+
+func (sysboxDriver) name() string { return "docker" }
+
+func (sysboxDriver) image(image string) string { return image }
+
+func (sysboxDriver) config(image string, args []string, volumes []string) map[string]interface{} {
+	return map[string]interface{}{
+		"image":        image,
+		"args":         args,
+		"volumes":      volumes,
+		"init":         true,
+		"runtime":      "sysbox-runc",
+		"network_mode": "bridge",
+	}
+}
+
+func (sysboxDriver) nestedDocker() bool { return true }
@@ -0,0 +1,127 @@
+package jobspec
+
+import (
+	"strconv"
+
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+	"github.com/hashicorp/nomad/api"
+)
+
+const (
+	// Weight of the GPU device affinities, leaving room for stronger user affinities
+	gpuAffinityWeight = 50
+
+	// Used when GPU options are left empty, matching the provider option defaults
+	defaultGPUArch               = "amd64"
+	defaultGPUShmSizeMB          = 2048
+	defaultGPUDriverCapabilities = "compute,utility"
+)
+
+// buildGPUDeviceRequest creates a Nomad device request for the configured GPU vendor
+// or device name. Model, memory and driver filters are device constraints because they
+// are attributes of the individual GPU, unlike compute capability (see buildGPUJobConstraints).
+func buildGPUDeviceRequest(options *opts.Options) *api.RequestedDevice {
+	count := uint64(options.GPUCount)
+	device := &api.RequestedDevice{
+		Name:  options.GPUDeviceName(),
+		Count: &count,
+	}
+
+	if options.GPUMinMemoryMB > 0 {
+		device.Constraints = append(device.Constraints,
+			api.NewConstraint("${device.attr.memory}", ">=", strconv.Itoa(options.GPUMinMemoryMB)+" MiB"))
+	}
+	if options.GPUModel != "" {
+		device.Constraints = append(device.Constraints,
+			api.NewConstraint("${device.model}", "regexp", options.GPUModel))
+	}
+	if options.GPUDriverVersion != "" {
+		device.Constraints = append(device.Constraints,
+			api.NewConstraint("${device.attr.driver_version}", "version", options.GPUDriverVersion))
+	}
+
+	if options.GPUPreferredModel != "" {
+		device.Affinities = append(device.Affinities,
+			api.NewAffinity("${device.model}", "regexp", options.GPUPreferredModel, gpuAffinityWeight))
+	}
+	if options.GPUPreferredMemoryMB > 0 {
+		device.Affinities = append(device.Affinities,
+			api.NewAffinity("${device.attr.memory}", ">=", strconv.Itoa(options.GPUPreferredMemoryMB)+" MiB", gpuAffinityWeight))
+	}
+
+	return device
+}
+
+// configureGPUTask sets the vendor-specific Docker config and environment
+// needed to use the requested GPUs inside the task
+func configureGPUTask(task *api.Task, options *opts.Options) {
+	// ML frameworks need more shared memory than Docker's 64MB default
+	shmSizeMB := options.GPUShmSizeMB
+	if shmSizeMB == 0 {
+		shmSizeMB = defaultGPUShmSizeMB
+	}
+	task.Config["shm_size"] = int64(shmSizeMB) * 1024 * 1024
+	if task.Env == nil {
+		task.Env = make(map[string]string)
+	}
+
+	switch options.GPUVendorName() {
+	case opts.GPUVendorNVIDIA:
+		task.Config["runtime"] = "nvidia"
+		// A specific device name (e.g. a MIG partition) must only expose the devices
+		// Nomad allocated, which the NVIDIA device plugin sets itself
+		if options.GPUDeviceName() == "nvidia/gpu" {
+			task.Env["NVIDIA_VISIBLE_DEVICES"] = "all"
+		}
+		driverCapabilities := options.GPUDriverCapabilities
+		if driverCapabilities == "" {
+			driverCapabilities = defaultGPUDriverCapabilities
+		}
+		task.Env["NVIDIA_DRIVER_CAPABILITIES"] = driverCapabilities
+	case opts.GPUVendorAMD:
+		// ROCm needs the kernel fusion driver and the DRI render nodes
+		task.Config["devices"] = []map[string]interface{}{
+			{"host_path": "/dev/kfd", "container_path": "/dev/kfd"},
+			{"host_path": "/dev/dri", "container_path": "/dev/dri"},
+		}
+		task.Config["group_add"] = []string{"video", "render"}
+	case opts.GPUVendorIntel:
+		task.Config["devices"] = []map[string]interface{}{
+			{"host_path": "/dev/dri", "container_path": "/dev/dri"},
+		}
+		task.Config["group_add"] = []string{"video", "render"}
+	}
+}
+
+// buildGPUJobConstraints returns job-level constraints for GPU workloads.
+// Compute capability uses a node meta attribute and must be a job constraint,
+// not a device constraint. It only applies to NVIDIA GPUs.
+func buildGPUJobConstraints(options *opts.Options) []*api.Constraint {
+	var constraints []*api.Constraint
+
+	arch := options.GPUArch
+	if arch == "" {
+		arch = defaultGPUArch
+	}
+	if arch != opts.GPUArchAny {
+		constraints = append(constraints, &api.Constraint{LTarget: "${attr.cpu.arch}", Operand: "=", RTarget: arch})
+	}
+
+	// Nodes marked with meta.gpu-dedicated=true are reserved for specific workloads
+	switch options.GPUDedicated {
+	case opts.GPUDedicatedAny:
+	case opts.GPUDedicatedRequire:
+		constraints = append(constraints, &api.Constraint{LTarget: "${meta.gpu-dedicated}", Operand: "=", RTarget: "true"})
+	default:
+		constraints = append(constraints, &api.Constraint{LTarget: "${meta.gpu-dedicated}", Operand: "!=", RTarget: "true"})
+	}
+
+	if options.GPUComputeCapability != "" {
+		constraints = append(constraints, &api.Constraint{
+			LTarget: "${meta.gpu_compute_capability}",
+			Operand: ">=",
+			RTarget: options.GPUComputeCapability,
+		})
+	}
+	return constraints
+}
@@ -0,0 +1,295 @@
+package jobspec
+
+import (
+	"testing"
+
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+	"github.com/hashicorp/nomad/api"
+)
+
+func TestBuildGPUDeviceRequest_NameAndCount(t *testing.T) {
+	options := &opts.Options{
+		GPUCount: 2,
+	}
+
+	device := buildGPUDeviceRequest(options)
+
+	if device.Name != "nvidia/gpu" {
+		t.Errorf("Expected device name 'nvidia/gpu', got %q", device.Name)
+	}
+	if device.Count == nil || *device.Count != 2 {
+		t.Errorf("Expected device count 2, got %v", device.Count)
+	}
+}
+
+func TestBuildGPUDeviceRequest_NoConstraints(t *testing.T) {
+	options := &opts.Options{
+		GPUCount:             1,
+		GPUComputeCapability: "7.5",
+	}
+
+	device := buildGPUDeviceRequest(options)
+
+	if len(device.Constraints) != 0 {
+		t.Errorf("Expected no device constraints, got %d", len(device.Constraints))
+	}
+}
+
+func TestBuildGPUDeviceRequest_Vendors(t *testing.T) {
+	tests := []struct {
+		vendor     string
+		deviceName string
+	}{
+		{"", "nvidia/gpu"},
+		{"nvidia", "nvidia/gpu"},
+		{"amd", "amd/gpu"},
+		{"intel", "intel/gpu"},
+		{"nvidia/gpu/A100-MIG-1g.10gb", "nvidia/gpu/A100-MIG-1g.10gb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.deviceName, func(t *testing.T) {
+			options := &opts.Options{
+				GPUCount:  1,
+				GPUVendor: tt.vendor,
+			}
+
+			device := buildGPUDeviceRequest(options)
+
+			if device.Name != tt.deviceName {
+				t.Errorf("Expected device name %q, got %q", tt.deviceName, device.Name)
+			}
+		})
+	}
+}
+
+func TestBuildGPUDeviceRequest_ConstraintsAndAffinities(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     opts.Options
+		constraints []api.Constraint
+		affinities  []api.Affinity
+	}{
+		{
+			name:    "no filters",
+			options: opts.Options{},
+		},
+		{
+			name:        "minimum memory",
+			options:     opts.Options{GPUMinMemoryMB: 24576},
+			constraints: []api.Constraint{{LTarget: "${device.attr.memory}", Operand: ">=", RTarget: "24576 MiB"}},
+		},
+		{
+			name:        "model regexp",
+			options:     opts.Options{GPUModel: "A100|H100"},
+			constraints: []api.Constraint{{LTarget: "${device.model}", Operand: "regexp", RTarget: "A100|H100"}},
+		},
+		{
+			name:        "driver version",
+			options:     opts.Options{GPUDriverVersion: ">= 535.0"},
+			constraints: []api.Constraint{{LTarget: "${device.attr.driver_version}", Operand: "version", RTarget: ">= 535.0"}},
+		},
+		{
+			name:    "preferred model and memory",
+			options: opts.Options{GPUPreferredModel: "H100", GPUPreferredMemoryMB: 81920},
+			affinities: []api.Affinity{
+				{LTarget: "${device.model}", Operand: "regexp", RTarget: "H100"},
+				{LTarget: "${device.attr.memory}", Operand: ">=", RTarget: "81920 MiB"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := tt.options
+			options.GPUCount = 1
+
+			device := buildGPUDeviceRequest(&options)
+
+			if len(device.Constraints) != len(tt.constraints) {
+				t.Fatalf("Expected %d constraints, got %d", len(tt.constraints), len(device.Constraints))
+			}
+			for i, want := range tt.constraints {
+				got := device.Constraints[i]
+				if got.LTarget != want.LTarget || got.Operand != want.Operand || got.RTarget != want.RTarget {
+					t.Errorf("Constraint %d: expected %v, got %v", i, want, *got)
+				}
+			}
+
+			if len(device.Affinities) != len(tt.affinities) {
+				t.Fatalf("Expected %d affinities, got %d", len(tt.affinities), len(device.Affinities))
+			}
+			for i, want := range tt.affinities {
+				got := device.Affinities[i]
+				if got.LTarget != want.LTarget || got.Operand != want.Operand || got.RTarget != want.RTarget {
+					t.Errorf("Affinity %d: expected %v, got %v", i, want, *got)
+				}
+				if got.Weight == nil || *got.Weight != gpuAffinityWeight {
+					t.Errorf("Affinity %d: expected weight %d, got %v", i, gpuAffinityWeight, got.Weight)
+				}
+			}
+		})
+	}
+}
+
+func TestConfigureGPUTask_Vendors(t *testing.T) {
+	tests := []struct {
+		vendor        string
+		runtime       interface{}
+		devices       int
+		visibleDevice string
+	}{
+		{"nvidia", "nvidia", 0, "all"},
+		{"nvidia/gpu/A100-MIG-1g.10gb", "nvidia", 0, ""},
+		{"amd", nil, 2, ""},
+		{"intel", nil, 1, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.vendor, func(t *testing.T) {
+			task := &api.Task{Config: map[string]interface{}{}}
+			options := &opts.Options{GPUVendor: tt.vendor}
+
+			configureGPUTask(task, options)
+
+			if task.Config["runtime"] != tt.runtime {
+				t.Errorf("Expected runtime %v, got %v", tt.runtime, task.Config["runtime"])
+			}
+			devices, _ := task.Config["devices"].([]map[string]interface{})
+			if len(devices) != tt.devices {
+				t.Errorf("Expected %d devices, got %d", tt.devices, len(devices))
+			}
+			if task.Env["NVIDIA_VISIBLE_DEVICES"] != tt.visibleDevice {
+				t.Errorf("Expected NVIDIA_VISIBLE_DEVICES %q, got %q", tt.visibleDevice, task.Env["NVIDIA_VISIBLE_DEVICES"])
+			}
+			if task.Config["shm_size"] != int64(2147483648) {
+				t.Errorf("Expected 2GB shm_size, got %v", task.Config["shm_size"])
+			}
+		})
+	}
+}
+
+func TestBuildGPUJobConstraints_NoComputeCapability(t *testing.T) {
+	options := &opts.Options{
+		GPUComputeCapability: "",
+	}
+
+	constraints := buildGPUJobConstraints(options)
+
+	if len(constraints) != 2 {
+		t.Fatalf("Expected 2 constraints, got %d", len(constraints))
+	}
+	if constraints[0].LTarget != "${attr.cpu.arch}" {
+		t.Errorf("Expected first constraint LTarget '${attr.cpu.arch}', got %q", constraints[0].LTarget)
+	}
+	if constraints[0].Operand != "=" || constraints[0].RTarget != "amd64" {
+		t.Errorf("Unexpected first constraint: operand=%q rtarget=%q", constraints[0].Operand, constraints[0].RTarget)
+	}
+	if constraints[1].LTarget != "${meta.gpu-dedicated}" {
+		t.Errorf("Expected second constraint LTarget '${meta.gpu-dedicated}', got %q", constraints[1].LTarget)
+	}
+	if constraints[1].Operand != "!=" || constraints[1].RTarget != "true" {
+		t.Errorf("Unexpected second constraint: operand=%q rtarget=%q", constraints[1].Operand, constraints[1].RTarget)
+	}
+}
+
+func TestBuildGPUJobConstraints_WithComputeCapability(t *testing.T) {
+	options := &opts.Options{
+		GPUComputeCapability: "7.5",
+	}
+
+	constraints := buildGPUJobConstraints(options)
+
+	if len(constraints) != 3 {
+		t.Fatalf("Expected 3 constraints, got %d", len(constraints))
+	}
+	// First two are arch and gpu-dedicated (verified in other test)
+	cc := constraints[2]
+	if cc.LTarget != "${meta.gpu_compute_capability}" {
+		t.Errorf("Expected compute capability LTarget '${meta.gpu_compute_capability}', got %q", cc.LTarget)
+	}
+	if cc.Operand != ">=" {
+		t.Errorf("Expected operand '>=', got %q", cc.Operand)
+	}
+	if cc.RTarget != "7.5" {
+		t.Errorf("Expected RTarget '7.5', got %q", cc.RTarget)
+	}
+}
+
+func TestBuildGPUJobConstraints_ArchAndDedicatedPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		arch        string
+		dedicated   string
+		constraints []api.Constraint
+	}{
+		{
+			name:      "defaults",
+			arch:      "",
+			dedicated: "",
+			constraints: []api.Constraint{
+				{LTarget: "${attr.cpu.arch}", Operand: "=", RTarget: "amd64"},
+				{LTarget: "${meta.gpu-dedicated}", Operand: "!=", RTarget: "true"},
+			},
+		},
+		{
+			name:      "arm64 on dedicated nodes",
+			arch:      "arm64",
+			dedicated: "require",
+			constraints: []api.Constraint{
+				{LTarget: "${attr.cpu.arch}", Operand: "=", RTarget: "arm64"},
+				{LTarget: "${meta.gpu-dedicated}", Operand: "=", RTarget: "true"},
+			},
+		},
+		{
+			name:        "any arch and any node",
+			arch:        "any",
+			dedicated:   "any",
+			constraints: nil,
+		},
+		{
+			name:      "any arch excluding dedicated nodes",
+			arch:      "any",
+			dedicated: "exclude",
+			constraints: []api.Constraint{
+				{LTarget: "${meta.gpu-dedicated}", Operand: "!=", RTarget: "true"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := &opts.Options{GPUArch: tt.arch, GPUDedicated: tt.dedicated}
+
+			constraints := buildGPUJobConstraints(options)
+
+			if len(constraints) != len(tt.constraints) {
+				t.Fatalf("Expected %d constraints, got %d", len(tt.constraints), len(constraints))
+			}
+			for i, want := range tt.constraints {
+				got := constraints[i]
+				if got.LTarget != want.LTarget || got.Operand != want.Operand || got.RTarget != want.RTarget {
+					t.Errorf("Constraint %d: expected %v, got %v", i, want, *got)
+				}
+			}
+		})
+	}
+}
+
+func TestConfigureGPUTask_ShmSizeAndDriverCapabilities(t *testing.T) {
+	task := &api.Task{Config: map[string]interface{}{}}
+	options := &opts.Options{
+		GPUVendor:             "nvidia",
+		GPUShmSizeMB:          8192,
+		GPUDriverCapabilities: "compute,utility,video",
+	}
+
+	configureGPUTask(task, options)
+
+	if task.Config["shm_size"] != int64(8192*1024*1024) {
+		t.Errorf("Expected 8GB shm_size, got %v", task.Config["shm_size"])
+	}
+	if task.Env["NVIDIA_DRIVER_CAPABILITIES"] != "compute,utility,video" {
+		t.Errorf("Expected driver capabilities compute,utility,video, got %q", task.Env["NVIDIA_DRIVER_CAPABILITIES"])
+	}
+}
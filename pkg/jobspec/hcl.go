@@ -0,0 +1,337 @@
+package jobspec
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// The Nomad API has no HCL encoder, so the job is written by walking the api
+// structs and following their hcl tags, the same tags jobspec2 uses to parse.
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// RenderHCL returns the job as an HCL2 job specification
+func RenderHCL(job *api.Job) string {
+	var b strings.Builder
+	id := ""
+	if job.ID != nil {
+		id = *job.ID
+	}
+	fmt.Fprintf(&b, "job %s {\n", quoteHCL(id))
+	// The job ID is the block label
+	writeHCLBody(&b, reflect.ValueOf(job).Elem(), 1, "id")
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// RenderVolumeHCL returns the CSI volume as a volume specification for `nomad volume create`.
+// Secrets are never rendered.
+func RenderVolumeHCL(vol *api.CSIVolume) string {
+	var b strings.Builder
+	writeHCLAttribute(&b, 0, "id", reflect.ValueOf(vol.ID))
+	writeHCLAttribute(&b, 0, "name", reflect.ValueOf(vol.Name))
+	if vol.Namespace != "" {
+		writeHCLAttribute(&b, 0, "namespace", reflect.ValueOf(vol.Namespace))
+	}
+	writeHCLAttribute(&b, 0, "type", reflect.ValueOf("csi"))
+	writeHCLAttribute(&b, 0, "plugin_id", reflect.ValueOf(vol.PluginID))
+	writeHCLAttribute(&b, 0, "capacity_min", reflect.ValueOf(fmt.Sprintf("%dMiB", vol.RequestedCapacityMin/1024/1024)))
+	writeHCLAttribute(&b, 0, "capacity_max", reflect.ValueOf(fmt.Sprintf("%dMiB", vol.RequestedCapacityMax/1024/1024)))
+	for _, capability := range vol.RequestedCapabilities {
+		b.WriteString("\ncapability {\n")
+		writeHCLAttribute(&b, 1, "access_mode", reflect.ValueOf(string(capability.AccessMode)))
+		writeHCLAttribute(&b, 1, "attachment_mode", reflect.ValueOf(string(capability.AttachmentMode)))
+		b.WriteString("}\n")
+	}
+	if vol.MountOptions != nil {
+		b.WriteString("\nmount_options {\n")
+		writeHCLBody(&b, reflect.ValueOf(vol.MountOptions).Elem(), 1)
+		b.WriteString("}\n")
+	}
+	if len(vol.Parameters) > 0 {
+		b.WriteString("\n")
+		writeHCLMapBlock(&b, 0, "parameters", reflect.ValueOf(vol.Parameters))
+	}
+	return b.String()
+}
+
+// hclField is a struct field with its hcl tag parsed
+type hclField struct {
+	name  string
+	label bool
+	value reflect.Value
+}
+
+// hclFields returns the fields of a struct that have an hcl tag, skipping the given names
+func hclFields(v reflect.Value, skip ...string) []hclField {
+	var fields []hclField
+	for i := 0; i < v.NumField(); i++ {
+		tag, ok := v.Type().Field(i).Tag.Lookup("hcl")
+		if !ok || tag == "-" {
+			continue
+		}
+		name, kind, _ := strings.Cut(tag, ",")
+		if slices.Contains(skip, name) {
+			continue
+		}
+		fields = append(fields, hclField{name: name, label: kind == "label", value: v.Field(i)})
+	}
+	return fields
+}
+
+// hclLabels returns the block labels of a struct
+func hclLabels(v reflect.Value) []string {
+	var labels []string
+	for _, f := range hclFields(v) {
+		if f.label {
+			labels = append(labels, fmt.Sprint(reflect.Indirect(f.value).Interface()))
+		}
+	}
+	return labels
+}
+
+// isHCLBlock reports whether values of type t are written as nested blocks
+func isHCLBlock(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return isHCLBlock(t.Elem())
+	case reflect.Struct:
+		return true
+	case reflect.Slice:
+		elem := t.Elem()
+		return elem.Kind() == reflect.Struct || (elem.Kind() == reflect.Ptr && elem.Elem().Kind() == reflect.Struct)
+	case reflect.Map:
+		return true
+	}
+	return false
+}
+
+// isHCLEmpty reports whether a field is unset and can be left out
+func isHCLEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr:
+		// Optional strings such as namespace and region are often set to ""
+		return v.IsNil() || (v.Elem().Kind() == reflect.String && v.Elem().Len() == 0)
+	case reflect.Interface:
+		return v.IsNil()
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	// Non-pointer fields can't distinguish unset from the zero value
+	return v.IsZero()
+}
+
+// writeHCLBody writes the attributes and then the nested blocks of a struct
+func writeHCLBody(b *strings.Builder, v reflect.Value, indent int, skip ...string) {
+	fields := hclFields(v, skip...)
+	// Blocks are separated from whatever precedes them in the body by a blank line
+	separate := false
+	for _, f := range fields {
+		if f.label || isHCLEmpty(f.value) || isHCLBlock(f.value.Type()) {
+			continue
+		}
+		writeHCLAttribute(b, indent, f.name, f.value)
+		separate = true
+	}
+	for _, f := range fields {
+		if f.label || isHCLEmpty(f.value) || !isHCLBlock(f.value.Type()) {
+			continue
+		}
+		name := f.name
+		// jobspec2 only reads port blocks and moves ports with a static value into the
+		// reserved ports itself
+		if name == "reserved_ports" {
+			name = "port"
+		}
+		writeHCLBlocks(b, indent, name, f.value, &separate)
+	}
+}
+
+// writeHCLBlocks writes one block per struct, or a block of attributes for maps of values
+func writeHCLBlocks(b *strings.Builder, indent int, name string, v reflect.Value, separate *bool) {
+	if v.Kind() != reflect.Ptr {
+		if *separate {
+			b.WriteString("\n")
+		}
+		*separate = true
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		writeHCLBlocks(b, indent, name, v.Elem(), separate)
+	case reflect.Struct:
+		writeHCLBlock(b, indent, name, v)
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			item := v.Index(i)
+			if item.Kind() == reflect.Ptr {
+				if item.IsNil() {
+					continue
+				}
+				item = item.Elem()
+			}
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			writeHCLBlock(b, indent, name, item)
+		}
+	case reflect.Map:
+		elem := v.Type().Elem()
+		if elem.Kind() == reflect.Ptr && elem.Elem().Kind() == reflect.Struct {
+			// Maps of structs such as group volumes are labeled blocks
+			for i, key := range sortedKeys(v) {
+				item := v.MapIndex(key)
+				if item.IsNil() {
+					continue
+				}
+				if i > 0 {
+					b.WriteString("\n")
+				}
+				writeHCLBlock(b, indent, name, item.Elem())
+			}
+			return
+		}
+		writeHCLMapBlock(b, indent, name, v)
+	}
+}
+
+func writeHCLBlock(b *strings.Builder, indent int, name string, v reflect.Value) {
+	pad := strings.Repeat("  ", indent)
+	b.WriteString(pad + name)
+	for _, label := range hclLabels(v) {
+		b.WriteString(" " + quoteHCL(label))
+	}
+	b.WriteString(" {\n")
+	writeHCLBody(b, v, indent+1)
+	b.WriteString(pad + "}\n")
+}
+
+// writeHCLMapBlock writes a map as a block of attributes, such as env, meta or config
+func writeHCLMapBlock(b *strings.Builder, indent int, name string, v reflect.Value) {
+	pad := strings.Repeat("  ", indent)
+	b.WriteString(pad + name + " {\n")
+	for _, key := range sortedKeys(v) {
+		writeHCLAttribute(b, indent+1, key.String(), v.MapIndex(key))
+	}
+	b.WriteString(pad + "}\n")
+}
+
+func writeHCLAttribute(b *strings.Builder, indent int, name string, v reflect.Value) {
+	pad := strings.Repeat("  ", indent)
+	value := hclValue(v, indent)
+	if v.Kind() != reflect.Ptr || !v.IsNil() {
+		if s, ok := reflect.Indirect(v).Interface().(string); ok {
+			value = stringHCL(s)
+		}
+	}
+	b.WriteString(pad + hclKey(name) + " = " + value + "\n")
+}
+
+// hclKey quotes map keys that are not valid identifiers, e.g. Ceph CSI parameters
+func hclKey(key string) string {
+	for i, r := range key {
+		isLetter := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		if !isLetter && (i == 0 || !(r == '-' || (r >= '0' && r <= '9'))) {
+			return quoteHCL(key)
+		}
+	}
+	return key
+}
+
+// hclValue returns the HCL expression for a value
+func hclValue(v reflect.Value, indent int) string {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "null"
+		}
+		v = v.Elem()
+	}
+
+	if v.Type() == durationType {
+		return quoteHCL(time.Duration(v.Int()).String())
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return quoteHCL(v.String())
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return fmt.Sprint(v.Interface())
+	case reflect.Slice, reflect.Array:
+		items := make([]string, v.Len())
+		multiline := false
+		for i := range items {
+			items[i] = hclValue(v.Index(i), indent+1)
+			multiline = multiline || strings.Contains(items[i], "\n")
+		}
+		if !multiline {
+			return "[" + strings.Join(items, ", ") + "]"
+		}
+		pad := strings.Repeat("  ", indent+1)
+		return "[\n" + pad + strings.Join(items, ",\n"+pad) + ",\n" + strings.Repeat("  ", indent) + "]"
+	case reflect.Map:
+		pad := strings.Repeat("  ", indent+1)
+		var out strings.Builder
+		out.WriteString("{\n")
+		for _, key := range sortedKeys(v) {
+			out.WriteString(pad + hclKey(key.String()) + " = " + hclValue(v.MapIndex(key), indent+1) + "\n")
+		}
+		out.WriteString(strings.Repeat("  ", indent) + "}")
+		return out.String()
+	}
+
+	return quoteHCL(fmt.Sprint(v.Interface()))
+}
+
+// stringHCL writes multi-line attributes such as templates as heredocs. Heredocs
+// can't be followed by a comma, so list items are always quoted.
+func stringHCL(s string) string {
+	if !strings.HasSuffix(s, "\n") || strings.Contains("\n"+s, "\nEOT\n") {
+		return quoteHCL(s)
+	}
+	return "<<EOT\n" + escapeHCLTemplate(s) + "EOT"
+}
+
+// quoteHCL returns s as a quoted HCL string
+func quoteHCL(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return escapeHCLTemplate(b.String())
+}
+
+// escapeHCLTemplate escapes interpolation and directive sequences so values like
+// "${node.class}" reach Nomad literally instead of being evaluated by the HCL parser
+func escapeHCLTemplate(s string) string {
+	s = strings.ReplaceAll(s, "${", "$${")
+	return strings.ReplaceAll(s, "%{", "%%{")
+}
+
+func sortedKeys(v reflect.Value) []reflect.Value {
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	return keys
+}
@@ -0,0 +1,119 @@
+package jobspec
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+	"github.com/hashicorp/nomad/api"
+)
+
+func TestRenderHCL_Golden(t *testing.T) {
+	for _, tt := range jobCases {
+		t.Run(tt.name, func(t *testing.T) {
+			options := baseOptions()
+			tt.modify(options)
+
+			job, err := Build(options)
+			if err != nil {
+				t.Fatalf("Build failed: %v", err)
+			}
+			got := RenderHCL(job)
+
+			if tt.golden {
+				checkGolden(t, filepath.Join("testdata", tt.name+".hcl"), []byte(got))
+				return
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("Expected %q in rendered job:\n%s", want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderHCL(t *testing.T) {
+	id := "example"
+	driver := "docker"
+	empty := ""
+	job := &api.Job{
+		ID:        &id,
+		Namespace: &empty,
+		Constraints: []*api.Constraint{
+			api.NewConstraint("${node.class}", "=", "workspace"),
+		},
+		TaskGroups: []*api.TaskGroup{{
+			Name: &id,
+			Tasks: []*api.Task{{
+				Name:   "main",
+				Driver: driver,
+				Env:    map[string]string{"GREETING": `say "hi"`},
+				Config: map[string]interface{}{
+					"args": []string{"-c", "echo %{x}"},
+				},
+				Templates: []*api.Template{{
+					EmbeddedTmpl: strPtr("line ${one}\nline two\n"),
+				}},
+			}},
+		}},
+	}
+
+	got := RenderHCL(job)
+
+	for _, want := range []string{
+		`job "example" {`,
+		`attribute = "$${node.class}"`,
+		`GREETING = "say \"hi\""`,
+		`args = ["-c", "echo %%{x}"]`,
+		"data = <<EOT\nline $${one}\nline two\nEOT\n",
+		`task "main" {`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in rendered job:\n%s", want, got)
+		}
+	}
+
+	// The ID is the job label and empty optional strings are left out
+	for _, unwanted := range []string{`id = "example"`, "namespace"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("Expected no %q in rendered job:\n%s", unwanted, got)
+		}
+	}
+}
+
+func TestRenderVolumeHCL(t *testing.T) {
+	options := baseOptions()
+	options.StorageMode = opts.StorageModePersistent
+	options.CSIClusterID = "cluster-1"
+	options.DiskMB = "10240"
+
+	vol, err := BuildCSIVolume(options)
+	if err != nil {
+		t.Fatalf("BuildCSIVolume failed: %v", err)
+	}
+	vol.Secrets = api.CSISecrets{"userKey": "s3cr3t"}
+
+	got := RenderVolumeHCL(vol)
+
+	for _, want := range []string{
+		`id = "devpod-devpod-test"`,
+		`type = "csi"`,
+		`plugin_id = "ceph-csi"`,
+		`capacity_min = "10240MiB"`,
+		`"csi.storage.k8s.io/fstype" = "ext4"`,
+		`clusterID = "cluster-1"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in rendered volume:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "s3cr3t") {
+		t.Error("Expected secrets to be left out of the rendered volume")
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,237 @@
+// Package jobspec builds the Nomad job for a DevPod workspace from the provider
+// options. It has no side effects, so the generated job can be tested and rendered
+// without a Nomad or Vault server.
+package jobspec
+
+import (
+	"fmt"
+	"strconv"
+
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+	"github.com/hashicorp/nomad/api"
+)
+
+const (
+	// Use Ubuntu as default since DevPod's Docker installation script supports it
+	// and we need Docker CLI for devcontainer support
+	defaultImage = "ubuntu:22.04"
+	defaultUser  = "root"
+
+	// Name of the CSI volume request in persistent storage mode
+	persistentVolumeName = "workspace"
+)
+
+// Build returns the Nomad job for the workspace described by options. In persistent
+// storage mode the job references the CSI volume options.GetVolumeID(), which the
+// caller must create before registering the job.
+func Build(options *opts.Options) (*api.Job, error) {
+	// DevPod run option overrides for job
+	image := workspaceImage(options)
+	env := map[string]string{}
+	// Create shared workspace dir, install dependencies, combine secrets into the shared location
+	// and copy them to workspace content directories as they're created.
+	// For persistent storage mode the script also syncs between /persistent and the shared path.
+	// The devcontainer's command runs under the script instead of replacing it, as the
+	// devcontainer's user, while the task runs as root for the setup.
+	runCmd := []string{"/bin/sh", "-c", buildBootstrapScript(options, options.WorkspacePath())}
+	if command := runCommand(options); len(command) > 0 {
+		// The first argument after the script is its $0
+		runCmd = append(append(runCmd, "devpod-bootstrap"), command...)
+	}
+	taskDriver := workspaceDriver(options)
+	if options.DriverOpts != nil {
+		if options.DriverOpts.Env != nil {
+			// Merge user env vars with our required env vars (ours take precedence)
+			for k, v := range options.DriverOpts.Env {
+				if _, exists := env[k]; !exists {
+					env[k] = v
+				}
+			}
+		}
+	}
+
+	cpu, err := strconv.Atoi(options.CPU)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NOMAD_CPU %q: %w", options.CPU, err)
+	}
+	mem, err := strconv.Atoi(options.MemoryMB)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NOMAD_MEMORYMB %q: %w", options.MemoryMB, err)
+	}
+	disk, err := strconv.Atoi(options.DiskMB)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NOMAD_DISKMB %q: %w", options.DiskMB, err)
+	}
+
+	jobResources := &api.Resources{
+		CPU:      &cpu,
+		MemoryMB: &mem,
+	}
+
+	// Add GPU device request if enabled
+	if options.GPUEnabled {
+		gpuDevice := buildGPUDeviceRequest(options)
+		jobResources.Devices = []*api.RequestedDevice{gpuDevice}
+	}
+
+	// Use the machine ID for job name and task group name
+	jobName := options.JobId
+
+	// Build Docker volumes list, followed by the devcontainer's mounts
+	dockerVolumes := buildTaskVolumes(options, taskDriver)
+	dockerVolumes = append(dockerVolumes, buildRunVolumes(options)...)
+
+	// Create the base task
+	task := &api.Task{
+		Name:      options.TaskName,
+		User:      defaultUser,
+		Env:       env,
+		Config:    taskDriver.config(taskDriver.image(image), runCmd, dockerVolumes),
+		Resources: jobResources,
+		Driver:    taskDriver.name(),
+	}
+	if len(dockerVolumes) == 0 {
+		delete(task.Config, "volumes")
+	}
+	applyRunOptions(task, options)
+
+	// Configure GPU support if enabled
+	if options.GPUEnabled {
+		configureGPUTask(task, options)
+	}
+
+	// Add Vault integration if configured
+	if len(options.VaultSecrets) > 0 {
+		task.Vault = buildTaskVault(options)
+
+		// Generate and attach Vault secret templates
+		task.Templates = generateVaultTemplates(options.VaultSecrets, options.VaultChangeMode, options.VaultChangeSignal)
+	}
+
+	// Add Nomad Variables templates if configured (uses the task's workload identity)
+	if len(options.NomadVariables) > 0 {
+		task.Templates = append(task.Templates, generateNomadVariableTemplates(options.NomadVariables, options.VaultChangeMode, options.VaultChangeSignal)...)
+	}
+
+	// Add Consul KV and service address template if configured
+	if len(options.ConsulKV) > 0 || len(options.ConsulServices) > 0 {
+		task.Templates = append(task.Templates, generateConsulTemplate(options.ConsulKV, options.ConsulServices, options.VaultChangeMode, options.VaultChangeSignal))
+	}
+
+	// Pull the image with the credentials of its registry
+	configureImagePull(task, image, options.JobId, options)
+
+	// Build task group with appropriate storage configuration
+	taskGroup := &api.TaskGroup{
+		Name:  &jobName,
+		Tasks: []*api.Task{task},
+	}
+
+	// Sidecars share the group's network namespace with the workspace so it reaches them
+	// on localhost. The workspace leads the group, stopping the sidecars when it exits.
+	if len(options.Sidecars) > 0 {
+		task.Leader = true
+		taskGroup.Tasks = append(taskGroup.Tasks, buildSidecarTasks(options)...)
+	}
+	configureNetwork(taskGroup, task, options)
+	taskGroup.Services = buildServices(options)
+	configureScheduling(taskGroup, task, options)
+
+	if options.StorageMode == opts.StorageModePersistent {
+		// Use CSI volume for persistent storage
+		// Mount at /persistent, sync with the workspace directory for Docker-in-Docker compatibility
+		volumeName := persistentVolumeName
+		mountPath := persistentMountPath
+		readOnly := false
+
+		taskGroup.Volumes = map[string]*api.VolumeRequest{
+			volumeName: {
+				Name:           volumeName,
+				Type:           "csi",
+				Source:         options.GetVolumeID(),
+				AccessMode:     string(api.CSIVolumeAccessModeSingleNodeWriter),
+				AttachmentMode: string(api.CSIVolumeAttachmentModeFilesystem),
+				MountOptions: &api.CSIMountOptions{
+					FSType: "ext4",
+				},
+			},
+		}
+
+		// Add volume mount to task
+		task.VolumeMounts = []*api.VolumeMount{
+			{
+				Volume:      &volumeName,
+				Destination: &mountPath,
+				ReadOnly:    &readOnly,
+			},
+		}
+	} else {
+		// Use ephemeral disk for non-persistent storage
+		taskGroup.EphemeralDisk = &api.EphemeralDisk{
+			SizeMB: &disk,
+		}
+	}
+
+	// Mount extra Nomad host volumes next to the CSI volume
+	addHostVolumes(taskGroup, task, options)
+
+	job := &api.Job{
+		ID:         &options.JobId,
+		Name:       &jobName,
+		Namespace:  &options.Namespace,
+		Region:     &options.Region,
+		TaskGroups: []*api.TaskGroup{taskGroup},
+	}
+
+	if len(options.Datacenters) > 0 {
+		job.Datacenters = options.Datacenters
+	}
+	if options.NodePool != "" {
+		job.NodePool = &options.NodePool
+	}
+	if options.Priority != 0 {
+		job.Priority = &options.Priority
+	}
+
+	// Add user-defined placement, then GPU-specific job constraints
+	job.Constraints = buildJobConstraints(options)
+	job.Affinities = buildJobAffinities(options)
+	job.Spreads = buildJobSpreads(options)
+	if options.GPUEnabled {
+		job.Constraints = append(job.Constraints, buildGPUJobConstraints(options)...)
+	}
+
+	return job, nil
+}
+
+// buildTaskVault returns the Vault block of a task rendering Vault secrets
+func buildTaskVault(options *opts.Options) *api.Vault {
+	vault := &api.Vault{
+		Policies:   options.VaultPolicies,
+		ChangeMode: &options.VaultChangeMode,
+	}
+	if options.VaultChangeMode == "signal" {
+		vault.ChangeSignal = &options.VaultChangeSignal
+	}
+
+	// Set optional Vault fields if provided
+	if options.VaultRole != "" {
+		vault.Role = options.VaultRole
+	}
+	if options.VaultNamespace != "" {
+		vault.Namespace = &options.VaultNamespace
+	}
+	return vault
+}
+
+// workspaceImage returns the image of the workspace task: the devcontainer's image,
+// then NOMAD_IMAGE, then the default
+func workspaceImage(options *opts.Options) string {
+	if options.DriverOpts != nil && options.DriverOpts.Image != "" {
+		return options.DriverOpts.Image
+	}
+	if options.Image != "" {
+		return options.Image
+	}
+	return defaultImage
+}
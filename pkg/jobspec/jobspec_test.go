@@ -0,0 +1,915 @@
+package jobspec
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+	"github.com/hashicorp/nomad/api"
+	"github.com/loft-sh/devpod/pkg/devcontainer/config"
+	"github.com/loft-sh/devpod/pkg/driver"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// baseOptions returns the options DefaultOptions produces without any configuration
+func baseOptions() *opts.Options {
+	return &opts.Options{
+		CPU:                   "200",
+		MemoryMB:              "512",
+		DiskMB:                "300",
+		JobId:                 "devpod-test",
+		TaskName:              "devpod-test",
+		VaultRole:             "nomad-workloads",
+		VaultChangeMode:       "restart",
+		VaultChangeSignal:     "SIGHUP",
+		VaultAuthMethod:       "token",
+		StorageMode:           opts.StorageModeEphemeral,
+		CSIPluginID:           "ceph-csi",
+		CSIPool:               "nomad",
+		GPUCount:              1,
+		GPUVendor:             opts.GPUVendorNVIDIA,
+		GPUArch:               "amd64",
+		GPUDedicated:          opts.GPUDedicatedExclude,
+		GPUShmSizeMB:          2048,
+		GPUDriverCapabilities: "compute,utility",
+		Priority:              50,
+		TaskDriver:            opts.TaskDriverDocker,
+		MountDockerSocket:     true,
+		MountDockerCerts:      true,
+		MountRegistryCA:       true,
+		RegistryCAPath:        "/usr/local/share/ca-certificates/registry.cluster.crt",
+		RestartAttempts:       2,
+		RestartInterval:       "30m",
+		RestartDelay:          "15s",
+		RescheduleInterval:    "1h",
+		KillTimeout:           "30s",
+		KillSignal:            "SIGTERM",
+	}
+}
+
+// jobCases are the option combinations rendered by the golden and HCL tests. The
+// golden cases cover storage modes, GPUs and secrets and are compared in full; the
+// others check the HCL they render for what sets them apart, so they don't need
+// refreshing when the bootstrap script changes.
+var jobCases = []struct {
+	name   string
+	modify func(o *opts.Options)
+	golden bool
+	want   []string
+}{
+	{
+		name:   "ephemeral",
+		modify: func(o *opts.Options) {},
+		golden: true,
+	},
+	{
+		name: "persistent",
+		modify: func(o *opts.Options) {
+			o.StorageMode = opts.StorageModePersistent
+			o.CSIClusterID = "cluster-1"
+			o.CSIVaultPath = "secret/data/ceph/csi"
+			o.DiskMB = "10240"
+		},
+		golden: true,
+	},
+	{
+		name: "gpu",
+		modify: func(o *opts.Options) {
+			o.GPUEnabled = true
+			o.GPUCount = 2
+			o.GPUComputeCapability = "8.0"
+			o.GPUMinMemoryMB = 24576
+			o.GPUPreferredModel = "H100"
+		},
+		golden: true,
+	},
+	{
+		name: "gpu_amd",
+		modify: func(o *opts.Options) {
+			o.GPUEnabled = true
+			o.GPUVendor = opts.GPUVendorAMD
+			o.GPUDedicated = opts.GPUDedicatedAny
+		},
+		want: []string{
+			`device "amd/gpu" {`,
+			`container_path = "/dev/kfd"`,
+			`group_add = ["video", "render"]`,
+		},
+	},
+	{
+		name: "vault",
+		modify: func(o *opts.Options) {
+			o.VaultAddr = "https://vault.example.com:8200"
+			o.VaultNamespace = "engineering"
+			o.VaultPolicies = []string{"devpod"}
+			o.VaultSecrets = []opts.VaultSecret{
+				{Path: "secret/data/db", Fields: map[string]string{"username": "DB_USER", "password": "DB_PASSWORD"}},
+				{Path: "secret/data/api", Fields: map[string]string{"key": "API_KEY"}, ChangeMode: "signal", ChangeSignal: "SIGUSR1", Splay: "30s"},
+			}
+			o.NomadVariables = []opts.NomadVariable{{Path: "nomad/jobs/shared", Fields: map[string]string{"token": "SHARED_TOKEN"}}}
+			o.ConsulServices = []opts.ConsulService{{Name: "postgres", AddressEnv: "DB_HOST", PortEnv: "DB_PORT"}}
+		},
+		golden: true,
+	},
+	{
+		name: "persistent_gpu_vault",
+		modify: func(o *opts.Options) {
+			o.StorageMode = opts.StorageModePersistent
+			o.CSIClusterID = "cluster-1"
+			o.GPUEnabled = true
+			o.VaultAddr = "https://vault.example.com:8200"
+			o.VaultPolicies = []string{"devpod"}
+			o.VaultChangeMode = "noop"
+			o.VaultSecrets = []opts.VaultSecret{{Path: "secret/data/db", Fields: map[string]string{"password": "DB_PASSWORD"}}}
+		},
+		golden: true,
+	},
+	{
+		name: "placement",
+		modify: func(o *opts.Options) {
+			o.Namespace = "dev"
+			o.Region = "eu"
+			o.Datacenters = []string{"dc1", "dc2"}
+			o.NodePool = "workspaces"
+			o.Priority = 80
+			o.Constraints = []opts.Constraint{{Attribute: "${node.class}", Value: "workspace"}}
+			o.Affinities = []opts.Affinity{{Attribute: "${meta.rack}", Value: "r1", Weight: 50}}
+			o.Spreads = []opts.Spread{{Attribute: "${node.datacenter}"}}
+		},
+		want: []string{
+			`region = "eu"`,
+			`namespace = "dev"`,
+			`priority = 80`,
+			`datacenters = ["dc1", "dc2"]`,
+			`node_pool = "workspaces"`,
+			`attribute = "$${node.class}"`,
+			"affinity {\n    attribute = \"$${meta.rack}\"",
+			"spread {\n    attribute = \"$${node.datacenter}\"",
+		},
+	},
+	{
+		name: "run_options",
+		modify: func(o *opts.Options) {
+			o.DriverOpts = &driver.RunOptions{
+				Image:          "mcr.microsoft.com/devcontainers/go:1.23",
+				User:           "vscode",
+				Entrypoint:     "/bin/sh",
+				Cmd:            []string{"-c", "sleep infinity"},
+				Env:            map[string]string{"GOFLAGS": "-mod=mod"},
+				CapAdd:         []string{"SYS_PTRACE"},
+				SecurityOpt:    []string{"seccomp=unconfined"},
+				Labels:         []string{"dev.containers.id=abc123", "devpod.workspace"},
+				WorkspaceMount: &config.Mount{Type: "bind", Source: "/srv/src/project", Target: "/workspaces/project"},
+				Mounts: []*config.Mount{
+					{Type: "volume", Source: "go-mod-cache", Target: "/go/pkg/mod"},
+					{Type: "bind", Source: "/opt/datasets", Target: "/datasets", Other: []string{"readonly"}},
+				},
+			}
+		},
+		want: []string{
+			`user = "root"`,
+			`set -- runuser -u 'vscode' -- \"$@\"`,
+			`cap_add = ["SYS_PTRACE"]`,
+			`security_opt = ["seccomp=unconfined"]`,
+			`"dev.containers.id" = "abc123"`,
+			`"/srv/src/project:/workspaces/project", "../alloc/devpod-volumes/go-mod-cache:/go/pkg/mod", "/opt/datasets:/datasets:ro"`,
+			`GOFLAGS = "-mod=mod"`,
+			`"devpod-bootstrap", "/bin/sh", "-c", "sleep infinity"]`,
+		},
+	},
+	{
+		name: "podman",
+		modify: func(o *opts.Options) {
+			o.TaskDriver = opts.TaskDriverPodman
+			o.Sidecars = []opts.Sidecar{{Name: "redis", Image: "redis:7"}}
+		},
+		want: []string{
+			`driver = "podman"`,
+			`image = "docker://ubuntu:22.04"`,
+			`image = "docker://redis:7"`,
+			"network {\n      mode = \"bridge\"",
+		},
+	},
+	{
+		name: "sysbox",
+		modify: func(o *opts.Options) {
+			o.TaskDriver = opts.TaskDriverSysbox
+		},
+		want: []string{
+			`runtime = "sysbox-runc"`,
+			`volumes = ["/etc/docker/certs.d:/etc/docker/certs.d:ro", "/usr/local/share/ca-certificates/registry.cluster.crt:/usr/local/share/ca-certificates/registry.cluster.crt:ro"]`,
+		},
+	},
+	{
+		name: "mounts",
+		modify: func(o *opts.Options) {
+			o.StorageMode = opts.StorageModePersistent
+			o.CSIClusterID = "cluster-1"
+			o.MountDockerSocket = false
+			o.MountDockerCerts = false
+			o.RegistryCAPath = "/etc/pki/registry-ca.crt"
+			o.ExtraVolumes = []opts.ExtraVolume{
+				{Source: "/opt/toolchains", Destination: "/opt/toolchains", ReadOnly: true},
+				{Type: opts.ExtraVolumeHost, Source: "datasets", Destination: "/datasets", ReadOnly: true},
+			}
+		},
+		want: []string{
+			`volumes = ["/etc/pki/registry-ca.crt:/usr/local/share/ca-certificates/registry-ca.crt:ro", "/opt/toolchains:/opt/toolchains:ro"]`,
+			"volume \"extra-1\" {\n      type = \"host\"\n      source = \"datasets\"",
+			"volume = \"extra-1\"\n        destination = \"/datasets\"",
+		},
+	},
+	{
+		name: "sidecars",
+		modify: func(o *opts.Options) {
+			o.Sidecars = []opts.Sidecar{
+				{
+					Name:     "postgres",
+					Image:    "postgres:16",
+					Env:      map[string]string{"POSTGRES_PASSWORD": "devpod"},
+					MemoryMB: 512,
+				},
+				{
+					Name:      "redis",
+					Image:     "redis:7",
+					Command:   "redis-server",
+					Args:      []string{"--save", ""},
+					Lifecycle: opts.SidecarLifecyclePoststart,
+				},
+			}
+		},
+		want: []string{
+			`leader = true`,
+			`task "postgres" {`,
+			`POSTGRES_PASSWORD = "devpod"`,
+			`hook = "poststart"`,
+			`command = "redis-server"`,
+			"network {\n      mode = \"bridge\"",
+		},
+	},
+	{
+		name: "network",
+		modify: func(o *opts.Options) {
+			o.NetworkMode = opts.NetworkModeBridge
+			o.Ports = []opts.Port{
+				{Label: "http", To: 3000},
+				{Label: "debug", To: 9229, Static: 9229},
+			}
+		},
+		want: []string{
+			"port \"debug\" {\n        static = 9229\n        to = 9229",
+			"port \"http\" {\n        to = 3000",
+		},
+	},
+	{
+		name: "services",
+		modify: func(o *opts.Options) {
+			o.NetworkMode = opts.NetworkModeBridge
+			o.Ports = []opts.Port{
+				{Label: "http", To: 3000},
+				{Label: "api_server", To: 8080},
+			}
+			o.ServiceProvider = opts.ServiceProviderConsul
+			o.ServiceTags = []string{"devpod", "owner={owner}"}
+			o.ServiceRouting = opts.ServiceRoutingTraefik
+			o.ServiceDomain = "dev.example.com"
+			o.Owner = "alex"
+		},
+		want: []string{
+			`name = "devpod-test-api-server"`,
+			`port = "api_server"`,
+			`provider = "consul"`,
+			"\"traefik.http.routers.devpod-test-http.rule=Host(`devpod-test.dev.example.com`)\"",
+			`devpod_owner = "alex"`,
+		},
+	},
+	{
+		name: "registry_auth",
+		modify: func(o *opts.Options) {
+			o.DriverOpts = &driver.RunOptions{Image: "ghcr.io/acme/dev:latest"}
+			o.Sidecars = []opts.Sidecar{{Name: "postgres", Image: "acme/postgres:16"}}
+			o.VaultPolicies = []string{"devpod-registry"}
+			o.RegistryAuth = []opts.RegistryAuth{
+				{Registry: "ghcr.io", VaultPath: "secret/data/registry/ghcr", SoftFail: true},
+				{Registry: "docker.io", Username: "acme", Token: "dckr_pat_example"},
+			}
+			o.ForcePull = true
+		},
+		want: []string{
+			`password = "$${DEVPOD_REGISTRY_0_PASSWORD}"`,
+			`auth_soft_fail = true`,
+			`force_pull = true`,
+			`destination = "secrets/registry-0.env"`,
+			`{{- with nomadVar "nomad/jobs/devpod-test" -}}`,
+			`export DEVPOD_REGISTRY_1_PASSWORD="{{ index . "registry_1_password" }}"`,
+		},
+	},
+}
+
+func TestBuild_Golden(t *testing.T) {
+	for _, tt := range jobCases {
+		if !tt.golden {
+			continue
+		}
+		t.Run(tt.name, func(t *testing.T) {
+			options := baseOptions()
+			tt.modify(options)
+
+			job, err := Build(options)
+			if err != nil {
+				t.Fatalf("Build failed: %v", err)
+			}
+
+			got, err := json.MarshalIndent(job, "", "  ")
+			if err != nil {
+				t.Fatalf("Failed to marshal job: %v", err)
+			}
+			got = append(got, '\n')
+
+			checkGolden(t, filepath.Join("testdata", tt.name+".json"), got)
+		})
+	}
+}
+
+// checkGolden compares got against the golden file, rewriting it first with -update
+func checkGolden(t *testing.T, golden string, got []byte) {
+	t.Helper()
+
+	if *update {
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatalf("Failed to update golden file: %v", err)
+		}
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("Failed to read golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Output does not match %s (run go test ./pkg/jobspec -update to accept the change)\ngot:\n%s", golden, got)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(o *opts.Options)
+		check  func(t *testing.T, job *api.Job)
+	}{
+		{
+			name:   "ephemeral uses ephemeral disk and no volumes",
+			modify: func(o *opts.Options) {},
+			check: func(t *testing.T, job *api.Job) {
+				group := job.TaskGroups[0]
+				if group.EphemeralDisk == nil || *group.EphemeralDisk.SizeMB != 300 {
+					t.Errorf("Expected 300MB ephemeral disk, got %v", group.EphemeralDisk)
+				}
+				if len(group.Volumes) != 0 || len(group.Tasks[0].VolumeMounts) != 0 {
+					t.Error("Expected no CSI volume in ephemeral mode")
+				}
+			},
+		},
+		{
+			name: "persistent mounts the workspace CSI volume",
+			modify: func(o *opts.Options) {
+				o.StorageMode = opts.StorageModePersistent
+			},
+			check: func(t *testing.T, job *api.Job) {
+				group := job.TaskGroups[0]
+				volume, ok := group.Volumes[persistentVolumeName]
+				if !ok || volume.Type != "csi" || volume.Source != "devpod-devpod-test" {
+					t.Fatalf("Expected CSI volume devpod-devpod-test, got %v", group.Volumes)
+				}
+				mounts := group.Tasks[0].VolumeMounts
+				if len(mounts) != 1 || *mounts[0].Destination != persistentMountPath {
+					t.Errorf("Expected volume mounted at %s, got %v", persistentMountPath, mounts)
+				}
+				if group.EphemeralDisk != nil {
+					t.Error("Expected no ephemeral disk in persistent mode")
+				}
+			},
+		},
+		{
+			name: "persistent runs the run options command under the bootstrap",
+			modify: func(o *opts.Options) {
+				o.StorageMode = opts.StorageModePersistent
+				o.DriverOpts = &driver.RunOptions{Cmd: []string{"sleep", "infinity"}}
+			},
+			check: func(t *testing.T, job *api.Job) {
+				args := job.TaskGroups[0].Tasks[0].Config["args"].([]string)
+				if len(args) != 6 || !strings.Contains(args[2], "rsync") || strings.Join(args[3:], " ") != "devpod-bootstrap sleep infinity" {
+					t.Errorf("Expected bootstrap script running the command, got %v", args)
+				}
+			},
+		},
+		{
+			name: "run options without a command keep the bootstrap alone",
+			modify: func(o *opts.Options) {
+				o.DriverOpts = &driver.RunOptions{Image: "ghcr.io/acme/dev:latest"}
+			},
+			check: func(t *testing.T, job *api.Job) {
+				task := job.TaskGroups[0].Tasks[0]
+				if args := task.Config["args"].([]string); len(args) != 3 {
+					t.Errorf("Expected only the bootstrap script, got %v", args)
+				}
+				for _, key := range []string{"labels", "cap_add", "security_opt"} {
+					if _, ok := task.Config[key]; ok {
+						t.Errorf("Expected no %s without run options setting it", key)
+					}
+				}
+			},
+		},
+		{
+			name: "run options mounts without host mounts",
+			modify: func(o *opts.Options) {
+				o.TaskDriver = opts.TaskDriverSysbox
+				o.MountDockerCerts = false
+				o.MountRegistryCA = false
+				o.DriverOpts = &driver.RunOptions{
+					Mounts: []*config.Mount{{Type: "volume", Target: "/var/lib/docker"}},
+				}
+			},
+			check: func(t *testing.T, job *api.Job) {
+				volumes := job.TaskGroups[0].Tasks[0].Config["volumes"].([]string)
+				if len(volumes) != 1 || volumes[0] != "../alloc/devpod-volumes/volume-0:/var/lib/docker" {
+					t.Errorf("Expected anonymous volume in the allocation directory, got %v", volumes)
+				}
+			},
+		},
+		{
+			name: "image from options without a devcontainer image",
+			modify: func(o *opts.Options) {
+				o.Image = "registry.example.com/devpod/base:1"
+				o.BootstrapMode = opts.BootstrapModePrebuilt
+			},
+			check: func(t *testing.T, job *api.Job) {
+				config := job.TaskGroups[0].Tasks[0].Config
+				if config["image"] != "registry.example.com/devpod/base:1" {
+					t.Errorf("Expected NOMAD_IMAGE, got %v", config["image"])
+				}
+				if strings.Contains(config["args"].([]string)[2], "apt-get") {
+					t.Error("Expected no apt-get with a prebuilt image")
+				}
+			},
+		},
+		{
+			name: "devcontainer image overrides options image",
+			modify: func(o *opts.Options) {
+				o.Image = "registry.example.com/devpod/base:1"
+				o.DriverOpts = &driver.RunOptions{Image: "mcr.microsoft.com/devcontainers/go:1"}
+			},
+			check: func(t *testing.T, job *api.Job) {
+				if image := job.TaskGroups[0].Tasks[0].Config["image"]; image != "mcr.microsoft.com/devcontainers/go:1" {
+					t.Errorf("Expected the devcontainer image, got %v", image)
+				}
+			},
+		},
+		{
+			name: "gpu adds device, runtime and job constraints",
+			modify: func(o *opts.Options) {
+				o.GPUEnabled = true
+				o.Constraints = []opts.Constraint{{Attribute: "${node.class}", Value: "gpu"}}
+			},
+			check: func(t *testing.T, job *api.Job) {
+				task := job.TaskGroups[0].Tasks[0]
+				if len(task.Resources.Devices) != 1 || task.Resources.Devices[0].Name != "nvidia/gpu" {
+					t.Errorf("Expected nvidia/gpu device request, got %v", task.Resources.Devices)
+				}
+				if task.Config["runtime"] != "nvidia" {
+					t.Errorf("Expected nvidia runtime, got %v", task.Config["runtime"])
+				}
+				// User constraints come first, then arch and gpu-dedicated
+				if len(job.Constraints) != 3 || job.Constraints[0].LTarget != "${node.class}" {
+					t.Errorf("Unexpected constraints: %v", job.Constraints)
+				}
+			},
+		},
+		{
+			name: "vault adds vault block and templates",
+			modify: func(o *opts.Options) {
+				o.VaultPolicies = []string{"devpod"}
+				o.VaultSecrets = []opts.VaultSecret{{Path: "secret/data/db", Fields: map[string]string{"password": "DB_PASSWORD"}}}
+				o.ConsulKV = []opts.ConsulKV{{Key: "dev/db/name", Env: "DB_NAME"}}
+			},
+			check: func(t *testing.T, job *api.Job) {
+				task := job.TaskGroups[0].Tasks[0]
+				if task.Vault == nil || task.Vault.Role != "nomad-workloads" || len(task.Vault.Policies) != 1 {
+					t.Errorf("Unexpected vault block: %v", task.Vault)
+				}
+				if len(task.Templates) != 2 {
+					t.Fatalf("Expected vault and consul templates, got %d", len(task.Templates))
+				}
+				if *task.Templates[0].DestPath != "secrets/vault-0.env" || *task.Templates[1].DestPath != "secrets/consul.env" {
+					t.Errorf("Unexpected template destinations %s, %s", *task.Templates[0].DestPath, *task.Templates[1].DestPath)
+				}
+			},
+		},
+		{
+			name: "no vault block without vault secrets",
+			modify: func(o *opts.Options) {
+				o.NomadVariables = []opts.NomadVariable{{Path: "nomad/jobs/shared", Fields: map[string]string{"token": "TOKEN"}}}
+			},
+			check: func(t *testing.T, job *api.Job) {
+				task := job.TaskGroups[0].Tasks[0]
+				if task.Vault != nil {
+					t.Error("Expected no vault block")
+				}
+				if len(task.Templates) != 1 {
+					t.Errorf("Expected 1 template, got %d", len(task.Templates))
+				}
+			},
+		},
+		{
+			name: "unset placement leaves Nomad defaults",
+			modify: func(o *opts.Options) {
+				o.Priority = 0
+			},
+			check: func(t *testing.T, job *api.Job) {
+				if job.Datacenters != nil || job.NodePool != nil || job.Priority != nil {
+					t.Errorf("Expected Nomad defaults, got datacenters %v node pool %v priority %v", job.Datacenters, job.NodePool, job.Priority)
+				}
+				if job.Constraints != nil || job.Affinities != nil || job.Spreads != nil {
+					t.Error("Expected no constraints, affinities or spreads")
+				}
+			},
+		},
+		{
+			name: "sidecars share the group network and follow the workspace",
+			modify: func(o *opts.Options) {
+				o.Sidecars = []opts.Sidecar{{Name: "redis", Image: "redis:7"}}
+			},
+			check: func(t *testing.T, job *api.Job) {
+				group := job.TaskGroups[0]
+				if len(group.Networks) != 1 || group.Networks[0].Mode != "bridge" {
+					t.Errorf("Expected bridge group network, got %v", group.Networks)
+				}
+				workspace := group.Tasks[0]
+				if !workspace.Leader {
+					t.Error("Expected the workspace task to lead the group")
+				}
+				if _, ok := workspace.Config["network_mode"]; ok {
+					t.Error("Expected no Docker network_mode with a group network")
+				}
+				if len(group.Tasks) != 2 {
+					t.Fatalf("Expected workspace and sidecar tasks, got %d", len(group.Tasks))
+				}
+				sidecar := group.Tasks[1]
+				if sidecar.Lifecycle == nil || sidecar.Lifecycle.Hook != "prestart" || !sidecar.Lifecycle.Sidecar {
+					t.Errorf("Expected prestart sidecar lifecycle, got %v", sidecar.Lifecycle)
+				}
+				if *sidecar.Resources.CPU != defaultSidecarCPU || *sidecar.Resources.MemoryMB != defaultSidecarMemoryMB {
+					t.Errorf("Expected default sidecar resources, got %d/%d", *sidecar.Resources.CPU, *sidecar.Resources.MemoryMB)
+				}
+			},
+		},
+		{
+			name:   "no group network without sidecars",
+			modify: func(o *opts.Options) {},
+			check: func(t *testing.T, job *api.Job) {
+				group := job.TaskGroups[0]
+				if group.Networks != nil || group.Tasks[0].Leader {
+					t.Error("Expected no group network and no leader without sidecars")
+				}
+				if group.Tasks[0].Config["network_mode"] != "bridge" {
+					t.Errorf("Expected Docker bridge network, got %v", group.Tasks[0].Config["network_mode"])
+				}
+			},
+		},
+		{
+			name: "ports are mapped by the driver without a network mode",
+			modify: func(o *opts.Options) {
+				o.Ports = []opts.Port{{Label: "http", To: 8080}}
+			},
+			check: func(t *testing.T, job *api.Job) {
+				group := job.TaskGroups[0]
+				if len(group.Networks) != 1 || group.Networks[0].Mode != "" || len(group.Networks[0].DynamicPorts) != 1 {
+					t.Fatalf("Expected group network with one dynamic port, got %v", group.Networks)
+				}
+				task := group.Tasks[0]
+				if task.Config["network_mode"] != "bridge" {
+					t.Errorf("Expected Docker bridge network, got %v", task.Config["network_mode"])
+				}
+				if ports, ok := task.Config["ports"].([]string); !ok || len(ports) != 1 || ports[0] != "http" {
+					t.Errorf("Expected driver port mapping for http, got %v", task.Config["ports"])
+				}
+			},
+		},
+		{
+			name: "host network mode uses the client's network",
+			modify: func(o *opts.Options) {
+				o.NetworkMode = opts.NetworkModeHost
+				o.Ports = []opts.Port{{Label: "http", Static: 8080}}
+			},
+			check: func(t *testing.T, job *api.Job) {
+				group := job.TaskGroups[0]
+				if len(group.Networks) != 1 || group.Networks[0].Mode != "host" {
+					t.Fatalf("Expected host group network, got %v", group.Networks)
+				}
+				if ports := group.Networks[0].ReservedPorts; len(ports) != 1 || ports[0].Value != 8080 {
+					t.Errorf("Expected reserved port 8080, got %v", ports)
+				}
+				task := group.Tasks[0]
+				if task.Config["network_mode"] != "host" {
+					t.Errorf("Expected Docker host network, got %v", task.Config["network_mode"])
+				}
+				if _, ok := task.Config["ports"]; ok {
+					t.Error("Expected no driver port mapping in host network mode")
+				}
+			},
+		},
+		{
+			name: "CNI network mode joins the group namespace",
+			modify: func(o *opts.Options) {
+				o.NetworkMode = "cni/devnet"
+				o.Sidecars = []opts.Sidecar{{Name: "redis", Image: "redis:7"}}
+			},
+			check: func(t *testing.T, job *api.Job) {
+				group := job.TaskGroups[0]
+				if len(group.Networks) != 1 || group.Networks[0].Mode != "cni/devnet" {
+					t.Errorf("Expected CNI group network, got %v", group.Networks)
+				}
+				if _, ok := group.Tasks[0].Config["network_mode"]; ok {
+					t.Error("Expected no Docker network_mode with a group network")
+				}
+			},
+		},
+		{
+			name: "fabio routing registers a URL prefix per port",
+			modify: func(o *opts.Options) {
+				o.Ports = []opts.Port{{Label: "http", To: 3000}}
+				o.ServiceProvider = opts.ServiceProviderNomad
+				o.ServiceTags = []string{"{job}-{label}"}
+				o.ServiceRouting = opts.ServiceRoutingFabio
+				o.ServiceDomain = "dev.example.com"
+			},
+			check: func(t *testing.T, job *api.Job) {
+				services := job.TaskGroups[0].Services
+				if len(services) != 1 {
+					t.Fatalf("Expected one service, got %d", len(services))
+				}
+				service := services[0]
+				if service.Name != "devpod-test-http" || service.PortLabel != "http" || service.Provider != "nomad" {
+					t.Errorf("Unexpected service %s on port %s with provider %s", service.Name, service.PortLabel, service.Provider)
+				}
+				want := []string{"devpod-test-http", "urlprefix-devpod-test.dev.example.com/"}
+				if strings.Join(service.Tags, ",") != strings.Join(want, ",") {
+					t.Errorf("Expected tags %v, got %v", want, service.Tags)
+				}
+			},
+		},
+		{
+			name: "ports are not registered without a service provider",
+			modify: func(o *opts.Options) {
+				o.Ports = []opts.Port{{Label: "http", To: 3000}}
+			},
+			check: func(t *testing.T, job *api.Job) {
+				if job.TaskGroups[0].Services != nil {
+					t.Errorf("Expected no services, got %v", job.TaskGroups[0].Services)
+				}
+			},
+		},
+		{
+			name:   "workspaces fail instead of moving to another node by default",
+			modify: func(o *opts.Options) {},
+			check: func(t *testing.T, job *api.Job) {
+				group := job.TaskGroups[0]
+				if *group.RestartPolicy.Attempts != 2 || *group.RestartPolicy.Mode != "fail" {
+					t.Errorf("Expected 2 restarts before failing, got %d in mode %s", *group.RestartPolicy.Attempts, *group.RestartPolicy.Mode)
+				}
+				if *group.ReschedulePolicy.Attempts != 0 || *group.ReschedulePolicy.Unlimited {
+					t.Error("Expected no rescheduling")
+				}
+				if *group.Disconnect.Replace {
+					t.Error("Expected disconnected workspaces not to be replaced")
+				}
+				if *group.Tasks[0].KillTimeout != 30*time.Second {
+					t.Errorf("Expected 30s kill timeout, got %v", *group.Tasks[0].KillTimeout)
+				}
+			},
+		},
+		{
+			name: "rescheduling and disconnect settings",
+			modify: func(o *opts.Options) {
+				o.RescheduleAttempts = 3
+				o.DisconnectStopAfter = "10m"
+				o.DisconnectReplace = true
+			},
+			check: func(t *testing.T, job *api.Job) {
+				group := job.TaskGroups[0]
+				if *group.ReschedulePolicy.Attempts != 3 || *group.ReschedulePolicy.Interval != time.Hour {
+					t.Errorf("Expected 3 reschedules per hour, got %d per %v", *group.ReschedulePolicy.Attempts, *group.ReschedulePolicy.Interval)
+				}
+				disconnect := group.Disconnect
+				if !*disconnect.Replace || disconnect.LostAfter != nil || *disconnect.StopOnClientAfter != 10*time.Minute {
+					t.Errorf("Unexpected disconnect strategy %+v", disconnect)
+				}
+			},
+		},
+		{
+			name: "docker mounts the client's Docker socket",
+			modify: func(o *opts.Options) {
+				o.TaskDriver = opts.TaskDriverDocker
+			},
+			check: func(t *testing.T, job *api.Job) {
+				task := job.TaskGroups[0].Tasks[0]
+				volumes := task.Config["volumes"].([]string)
+				if task.Driver != "docker" || task.Config["privileged"] != true || volumes[0] != "/var/run/docker.sock:/var/run/docker.sock" {
+					t.Errorf("Expected privileged docker task with the Docker socket, got %s %v", task.Driver, task.Config)
+				}
+			},
+		},
+		{
+			name: "podman runs a nested Docker daemon",
+			modify: func(o *opts.Options) {
+				o.TaskDriver = opts.TaskDriverPodman
+				o.DriverOpts = &driver.RunOptions{Image: "ghcr.io/acme/dev:latest", Cmd: []string{"sleep", "infinity"}}
+			},
+			check: func(t *testing.T, job *api.Job) {
+				task := job.TaskGroups[0].Tasks[0]
+				if task.Driver != "podman" || task.Config["image"] != "docker://ghcr.io/acme/dev:latest" {
+					t.Errorf("Expected podman task with docker:// image, got %s %v", task.Driver, task.Config["image"])
+				}
+				for _, v := range task.Config["volumes"].([]string) {
+					if strings.Contains(v, "docker.sock") || strings.HasPrefix(v, "/tmp/devpod-workspaces") {
+						t.Errorf("Expected no host Docker socket or workspace path, got %s", v)
+					}
+				}
+				// The run options command must not replace the bootstrap starting dockerd
+				args := task.Config["args"].([]string)
+				if len(args) != 6 || !strings.Contains(args[2], "dockerd") || args[4] != "sleep" {
+					t.Errorf("Expected bootstrap script starting dockerd, got %v", args)
+				}
+			},
+		},
+		{
+			name: "sysbox uses the sysbox runtime without privileged mode",
+			modify: func(o *opts.Options) {
+				o.TaskDriver = opts.TaskDriverSysbox
+			},
+			check: func(t *testing.T, job *api.Job) {
+				task := job.TaskGroups[0].Tasks[0]
+				if task.Driver != "docker" || task.Config["runtime"] != "sysbox-runc" {
+					t.Errorf("Expected docker task with sysbox-runc, got %s %v", task.Driver, task.Config["runtime"])
+				}
+				if _, ok := task.Config["privileged"]; ok {
+					t.Error("Expected no privileged mode with sysbox")
+				}
+			},
+		},
+		{
+			name: "nested Docker drivers never mount the Docker socket",
+			modify: func(o *opts.Options) {
+				o.TaskDriver = opts.TaskDriverSysbox
+				o.MountDockerCerts = false
+				o.MountRegistryCA = false
+			},
+			check: func(t *testing.T, job *api.Job) {
+				if volumes, ok := job.TaskGroups[0].Tasks[0].Config["volumes"]; ok {
+					t.Errorf("Expected no volumes, got %v", volumes)
+				}
+			},
+		},
+		{
+			name: "run options override image and env, the task keeps running as root",
+			modify: func(o *opts.Options) {
+				o.DriverOpts = &driver.RunOptions{
+					Image: "golang:1.23",
+					User:  "vscode",
+					Env:   map[string]string{"FOO": "bar"},
+				}
+			},
+			check: func(t *testing.T, job *api.Job) {
+				task := job.TaskGroups[0].Tasks[0]
+				if task.Config["image"] != "golang:1.23" || task.User != defaultUser || task.Env["FOO"] != "bar" {
+					t.Errorf("Unexpected task: image %v user %s env %v", task.Config["image"], task.User, task.Env)
+				}
+			},
+		},
+		{
+			name: "registry auth only applies to the image's registry",
+			modify: func(o *opts.Options) {
+				o.RegistryAuth = []opts.RegistryAuth{{Registry: "ghcr.io", Username: "acme", Password: "secret"}}
+			},
+			check: func(t *testing.T, job *api.Job) {
+				config := job.TaskGroups[0].Tasks[0].Config
+				if _, ok := config["auth"]; ok {
+					t.Errorf("Expected no auth for a Docker Hub image, got %v", config["auth"])
+				}
+				if _, ok := config["force_pull"]; ok {
+					t.Error("Expected no force_pull by default")
+				}
+			},
+		},
+		{
+			name: "registry auth from vault keeps the secrets vault block",
+			modify: func(o *opts.Options) {
+				o.VaultPolicies = []string{"devpod"}
+				o.VaultSecrets = []opts.VaultSecret{{Path: "secret/data/aws", Fields: map[string]string{"key": "AWS_KEY"}}}
+				o.RegistryAuth = []opts.RegistryAuth{{Registry: "index.docker.io", VaultPath: "secret/data/registry/hub"}}
+			},
+			check: func(t *testing.T, job *api.Job) {
+				task := job.TaskGroups[0].Tasks[0]
+				if task.Vault == nil || *task.Vault.ChangeMode != "restart" {
+					t.Errorf("Expected the Vault block of the secrets, got %v", task.Vault)
+				}
+				if len(task.Templates) != 2 || *task.Templates[1].DestPath != "secrets/registry-0.env" {
+					t.Errorf("Expected the registry template after the secrets, got %d templates", len(task.Templates))
+				}
+				auth := task.Config["auth"].([]map[string]interface{})
+				if auth[0]["password"] != "${DEVPOD_REGISTRY_0_PASSWORD}" {
+					t.Errorf("Expected password from the template, got %v", auth)
+				}
+			},
+		},
+		{
+			name: "registry token is read from the job's variable",
+			modify: func(o *opts.Options) {
+				o.TaskDriver = opts.TaskDriverPodman
+				o.DriverOpts = &driver.RunOptions{Image: "registry.example.com:5000/team/dev"}
+				o.RegistryAuth = []opts.RegistryAuth{{Registry: "registry.example.com:5000", Token: "glpat-example"}}
+			},
+			check: func(t *testing.T, job *api.Job) {
+				task := job.TaskGroups[0].Tasks[0]
+				auth := task.Config["auth"].([]map[string]interface{})
+				if auth[0]["username"] != "${DEVPOD_REGISTRY_0_USERNAME}" || auth[0]["password"] != "${DEVPOD_REGISTRY_0_PASSWORD}" {
+					t.Errorf("Expected credentials from the template, got %v", auth)
+				}
+				if task.Vault != nil || len(task.Templates) != 1 || !strings.Contains(*task.Templates[0].EmbeddedTmpl, `nomadVar "nomad/jobs/devpod-test"`) {
+					t.Errorf("Expected only the Nomad Variable template, got %v %d templates", task.Vault, len(task.Templates))
+				}
+				data, _ := json.Marshal(job)
+				if strings.Contains(string(data), "glpat-example") {
+					t.Error("Expected the token not to appear in the job")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := baseOptions()
+			tt.modify(options)
+
+			job, err := Build(options)
+			if err != nil {
+				t.Fatalf("Build failed: %v", err)
+			}
+
+			if *job.ID != "devpod-test" || *job.TaskGroups[0].Name != "devpod-test" {
+				t.Errorf("Expected job and group named after the machine ID, got %s / %s", *job.ID, *job.TaskGroups[0].Name)
+			}
+			tt.check(t, job)
+		})
+	}
+}
+
+func TestBuild_InvalidResources(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(o *opts.Options)
+	}{
+		{"cpu", func(o *opts.Options) { o.CPU = "two" }},
+		{"memory", func(o *opts.Options) { o.MemoryMB = "" }},
+		{"disk", func(o *opts.Options) { o.DiskMB = "1GB" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := baseOptions()
+			tt.modify(options)
+
+			if _, err := Build(options); err == nil {
+				t.Error("Expected error for invalid resource value")
+			}
+		})
+	}
+}
+
+func TestBuild_Deterministic(t *testing.T) {
+	options := baseOptions()
+	jobCases[4].modify(options) // vault, with multi-field secrets
+
+	first, err := Build(options)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	want, _ := json.Marshal(first)
+
+	for i := 0; i < 20; i++ {
+		job, err := Build(options)
+		if err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		got, _ := json.Marshal(job)
+		if !bytes.Equal(got, want) {
+			t.Fatal("Expected identical jobs for identical options")
+		}
+	}
+}
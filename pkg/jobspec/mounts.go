@@ -0,0 +1,89 @@
+package jobspec
+
+import (
+	"fmt"
+	"path"
+
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+	"github.com/hashicorp/nomad/api"
+)
+
+const (
+	// Docker registry certificates read by the Docker daemon
+	dockerCertsPath = "/etc/docker/certs.d"
+
+	// Directory update-ca-certificates reads additional CA certificates from
+	caCertificatesDir = "/usr/local/share/ca-certificates"
+)
+
+// buildTaskVolumes returns the driver volumes of the workspace task: the host
+// mounts enabled in the options followed by the extra bind mounts
+func buildTaskVolumes(options *opts.Options, taskDriver taskDriver) []string {
+	var volumes []string
+
+	hostDocker := !taskDriver.nestedDocker() && options.MountDockerSocket
+	if hostDocker {
+		// Mount Docker socket from host for Docker-in-Docker support
+		volumes = append(volumes, dockerSocketPath+":"+dockerSocketPath)
+	}
+	if options.MountDockerCerts {
+		// Mount Docker registry certificates for Docker daemon
+		volumes = append(volumes, dockerCertsPath+":"+dockerCertsPath+":ro")
+	}
+	if options.MountRegistryCA {
+		// Mount CA certificate source file so update-ca-certificates includes it
+		caPath := path.Join(caCertificatesDir, path.Base(options.RegistryCAPath))
+		volumes = append(volumes, options.RegistryCAPath+":"+caPath+":ro")
+	}
+	if hostDocker {
+		// Include host bind mount for Docker-in-Docker compatibility
+		// Docker looks for bind mount paths on the HOST, so the workspace directory must be
+		// at the same path on the host and in the container. Only this workspace's own
+		// directory is mounted so workspaces on the same client can't see each other's files.
+		// For persistent mode, we sync data between this path and the CSI volume at /persistent
+		volumes = append(volumes, options.WorkspacePath()+":"+options.WorkspacePath())
+	}
+
+	for _, v := range options.ExtraVolumes {
+		if v.Type == opts.ExtraVolumeHost {
+			continue
+		}
+		volume := v.Source + ":" + v.Destination
+		if v.ReadOnly {
+			volume += ":ro"
+		}
+		volumes = append(volumes, volume)
+	}
+
+	return volumes
+}
+
+// addHostVolumes requests the extra Nomad host volumes in the group and mounts
+// them into the workspace task
+func addHostVolumes(group *api.TaskGroup, task *api.Task, options *opts.Options) {
+	for i, v := range options.ExtraVolumes {
+		if v.Type != opts.ExtraVolumeHost {
+			continue
+		}
+
+		// Indexed names keep requests unique when a host volume is mounted twice
+		name := fmt.Sprintf("extra-%d", i)
+		destination := v.Destination
+		readOnly := v.ReadOnly
+
+		if group.Volumes == nil {
+			group.Volumes = map[string]*api.VolumeRequest{}
+		}
+		group.Volumes[name] = &api.VolumeRequest{
+			Name:     name,
+			Type:     "host",
+			Source:   v.Source,
+			ReadOnly: readOnly,
+		}
+		task.VolumeMounts = append(task.VolumeMounts, &api.VolumeMount{
+			Volume:      &name,
+			Destination: &destination,
+			ReadOnly:    &readOnly,
+		})
+	}
+}
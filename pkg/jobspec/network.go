@@ -0,0 +1,43 @@
+package jobspec
+
+import (
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+	"github.com/hashicorp/nomad/api"
+)
+
+// configureNetwork adds the group network with the exposed ports and sets the
+// workspace task's driver networking for the network mode in the options
+func configureNetwork(group *api.TaskGroup, task *api.Task, options *opts.Options) {
+	mode := options.NetworkMode
+	// Sidecars reach the workspace on localhost, which needs a shared network namespace
+	if mode == "" && len(options.Sidecars) > 0 {
+		mode = opts.NetworkModeBridge
+	}
+
+	network := &api.NetworkResource{Mode: mode}
+	var labels []string
+	for _, p := range options.Ports {
+		port := api.Port{Label: p.Label, Value: p.Static, To: p.To}
+		if p.Static != 0 {
+			network.ReservedPorts = append(network.ReservedPorts, port)
+		} else {
+			network.DynamicPorts = append(network.DynamicPorts, port)
+		}
+		labels = append(labels, p.Label)
+	}
+
+	switch mode {
+	case "":
+		// Docker's bridge network; the driver maps the ports into the container
+		if len(labels) == 0 {
+			return
+		}
+		task.Config["ports"] = labels
+	case opts.NetworkModeHost:
+		task.Config["network_mode"] = opts.NetworkModeHost
+	default:
+		// Bridge and CNI networks put all tasks of the group in one network namespace
+		delete(task.Config, "network_mode")
+	}
+	group.Networks = []*api.NetworkResource{network}
+}
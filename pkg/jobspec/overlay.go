@@ -0,0 +1,324 @@
+package jobspec
+
+import (
+	"encoding/json"
+	"fmt"
+
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+	"github.com/hashicorp/nomad/api"
+)
+
+// Name of the template task that is merged into the workspace task instead of being
+// added as a new task. The workspace task itself is named after the machine ID.
+const workspaceTaskAlias = "workspace"
+
+// ParseJSONJobTemplate parses a job template in Nomad's JSON job format, either
+// wrapped in a "Job" key as accepted by `nomad job run -json` or as a bare job.
+func ParseJSONJobTemplate(data []byte) (*api.Job, error) {
+	var wrapped struct {
+		Job *api.Job
+	}
+	if err := json.Unmarshal(data, &wrapped); err != nil {
+		return nil, fmt.Errorf("parse job template: %w", err)
+	}
+	if wrapped.Job != nil {
+		return wrapped.Job, nil
+	}
+
+	var job api.Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("parse job template: %w", err)
+	}
+	return &job, nil
+}
+
+// ApplyJobTemplate merges a user-supplied job template into the generated job.
+// The provider keeps the job's identity and everything it needs to run the
+// workspace; the template adds to lists and maps, and its settings replace the
+// provider's where both set the same field. Template tasks are added alongside
+// the workspace task, except a task named "workspace", which is merged into it.
+func ApplyJobTemplate(job *api.Job, tmpl *api.Job) error {
+	if tmpl.Type != nil && *tmpl.Type != "" && *tmpl.Type != api.JobTypeService {
+		return fmt.Errorf("job template has type %s (must be %s)", *tmpl.Type, api.JobTypeService)
+	}
+	if tmpl.Periodic != nil || tmpl.ParameterizedJob != nil || tmpl.Multiregion != nil {
+		return fmt.Errorf("job template cannot be periodic, parameterized or multiregion")
+	}
+	if len(tmpl.TaskGroups) > 1 {
+		return fmt.Errorf("job template has %d groups (must have at most one, merged into the workspace group)", len(tmpl.TaskGroups))
+	}
+
+	if tmpl.Priority != nil {
+		job.Priority = tmpl.Priority
+	}
+	if len(tmpl.Datacenters) > 0 {
+		job.Datacenters = tmpl.Datacenters
+	}
+	if tmpl.NodePool != nil && *tmpl.NodePool != "" {
+		job.NodePool = tmpl.NodePool
+	}
+	if tmpl.Update != nil {
+		job.Update = tmpl.Update
+	}
+	job.Meta = mergeMap(job.Meta, tmpl.Meta, true)
+	job.Constraints = append(job.Constraints, tmpl.Constraints...)
+	job.Affinities = append(job.Affinities, tmpl.Affinities...)
+	job.Spreads = append(job.Spreads, tmpl.Spreads...)
+
+	if len(tmpl.TaskGroups) == 1 {
+		return applyGroupTemplate(job.TaskGroups[0], tmpl.TaskGroups[0])
+	}
+	return nil
+}
+
+func applyGroupTemplate(group *api.TaskGroup, tmpl *api.TaskGroup) error {
+	// Every workspace is a single allocation
+	if tmpl.Count != nil && *tmpl.Count != 1 {
+		return fmt.Errorf("job template group has count %d (must be 1)", *tmpl.Count)
+	}
+
+	for name, volume := range tmpl.Volumes {
+		if _, exists := group.Volumes[name]; exists {
+			return fmt.Errorf("job template volume %q conflicts with the workspace volume", name)
+		}
+		if group.Volumes == nil {
+			group.Volumes = map[string]*api.VolumeRequest{}
+		}
+		group.Volumes[name] = volume
+	}
+
+	if tmpl.RestartPolicy != nil {
+		group.RestartPolicy = tmpl.RestartPolicy
+	}
+	if tmpl.ReschedulePolicy != nil {
+		group.ReschedulePolicy = tmpl.ReschedulePolicy
+	}
+	if tmpl.Disconnect != nil {
+		group.Disconnect = tmpl.Disconnect
+	}
+	if err := applyLegacyDisconnect(group, tmpl); err != nil {
+		return err
+	}
+	if tmpl.Update != nil {
+		group.Update = tmpl.Update
+	}
+	if tmpl.Migrate != nil {
+		group.Migrate = tmpl.Migrate
+	}
+	if tmpl.Consul != nil {
+		group.Consul = tmpl.Consul
+	}
+	if tmpl.ShutdownDelay != nil {
+		group.ShutdownDelay = tmpl.ShutdownDelay
+	}
+	if len(tmpl.Networks) > 0 {
+		if err := mergeNetwork(group, tmpl.Networks); err != nil {
+			return err
+		}
+	}
+	if tmpl.EphemeralDisk != nil {
+		if group.EphemeralDisk == nil {
+			group.EphemeralDisk = &api.EphemeralDisk{}
+		}
+		if tmpl.EphemeralDisk.Sticky != nil {
+			group.EphemeralDisk.Sticky = tmpl.EphemeralDisk.Sticky
+		}
+		if tmpl.EphemeralDisk.Migrate != nil {
+			group.EphemeralDisk.Migrate = tmpl.EphemeralDisk.Migrate
+		}
+		if tmpl.EphemeralDisk.SizeMB != nil {
+			group.EphemeralDisk.SizeMB = tmpl.EphemeralDisk.SizeMB
+		}
+	}
+
+	group.Meta = mergeMap(group.Meta, tmpl.Meta, true)
+	group.Constraints = append(group.Constraints, tmpl.Constraints...)
+	group.Affinities = append(group.Affinities, tmpl.Affinities...)
+	group.Spreads = append(group.Spreads, tmpl.Spreads...)
+	group.Services = append(group.Services, tmpl.Services...)
+
+	workspaceTask := group.Tasks[0]
+	for _, task := range tmpl.Tasks {
+		if task.Name == workspaceTaskAlias || task.Name == workspaceTask.Name {
+			if err := applyTaskTemplate(workspaceTask, task); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, existing := range group.Tasks {
+			if existing.Name == task.Name {
+				return fmt.Errorf("job template task %q is defined more than once", task.Name)
+			}
+		}
+		group.Tasks = append(group.Tasks, task)
+	}
+
+	return nil
+}
+
+// applyLegacyDisconnect translates the template's max_client_disconnect and
+// stop_after_client_disconnect into the group's disconnect block, since Nomad
+// rejects groups that set them next to one
+func applyLegacyDisconnect(group *api.TaskGroup, tmpl *api.TaskGroup) error {
+	if tmpl.MaxClientDisconnect == nil && tmpl.StopAfterClientDisconnect == nil {
+		return nil
+	}
+	if tmpl.Disconnect != nil {
+		return fmt.Errorf("job template group sets max_client_disconnect or stop_after_client_disconnect next to a disconnect block (use the disconnect block only)")
+	}
+	if tmpl.MaxClientDisconnect != nil && tmpl.StopAfterClientDisconnect != nil {
+		return fmt.Errorf("job template group sets both max_client_disconnect and stop_after_client_disconnect")
+	}
+
+	if group.Disconnect == nil {
+		group.Disconnect = &api.DisconnectStrategy{}
+	}
+	// The disconnect block only takes one of the two timeouts
+	if tmpl.MaxClientDisconnect != nil {
+		group.Disconnect.LostAfter = tmpl.MaxClientDisconnect
+		group.Disconnect.StopOnClientAfter = nil
+	} else {
+		group.Disconnect.StopOnClientAfter = tmpl.StopAfterClientDisconnect
+		group.Disconnect.LostAfter = nil
+	}
+	return nil
+}
+
+// mergeNetwork adds the ports, DNS and hostname of the template network to the
+// workspace network. Its mode must match the provider's: sidecars rely on the shared
+// namespace, and services and driver port mappings on the provider's port labels.
+// Without a network mode the driver maps the template's ports into the workspace
+// container, like the ports from the options.
+func mergeNetwork(group *api.TaskGroup, networks []*api.NetworkResource) error {
+	if len(networks) > 1 {
+		return fmt.Errorf("job template group has %d networks (must have at most one, merged into the workspace network)", len(networks))
+	}
+	tmpl := networks[0]
+
+	if len(group.Networks) == 0 {
+		group.Networks = []*api.NetworkResource{{}}
+	}
+	network := group.Networks[0]
+	if tmpl.Mode != "" && tmpl.Mode != network.Mode {
+		return fmt.Errorf("job template network mode %s conflicts with the workspace network mode %q (set NOMAD_NETWORK_MODE instead)", tmpl.Mode, network.Mode)
+	}
+
+	labels := map[string]bool{}
+	for _, ports := range [][]api.Port{network.ReservedPorts, network.DynamicPorts} {
+		for _, port := range ports {
+			labels[port.Label] = true
+		}
+	}
+	for _, ports := range [][]api.Port{tmpl.ReservedPorts, tmpl.DynamicPorts} {
+		for _, port := range ports {
+			if labels[port.Label] {
+				return fmt.Errorf("job template port %q conflicts with a workspace port", port.Label)
+			}
+			labels[port.Label] = true
+		}
+	}
+	network.ReservedPorts = append(network.ReservedPorts, tmpl.ReservedPorts...)
+	network.DynamicPorts = append(network.DynamicPorts, tmpl.DynamicPorts...)
+	if network.Mode == "" {
+		task := group.Tasks[0]
+		ports, _ := task.Config["ports"].([]string)
+		for _, tmplPorts := range [][]api.Port{tmpl.ReservedPorts, tmpl.DynamicPorts} {
+			for _, port := range tmplPorts {
+				ports = append(ports, port.Label)
+			}
+		}
+		if len(ports) > 0 {
+			task.Config["ports"] = ports
+		}
+	}
+
+	if tmpl.DNS != nil {
+		network.DNS = tmpl.DNS
+	}
+	if tmpl.Hostname != "" {
+		network.Hostname = tmpl.Hostname
+	}
+	return nil
+}
+
+// applyTaskTemplate merges a template task into the workspace task. The image,
+// driver, user, resources and Vault block stay as configured by the provider, and
+// the kill settings must still let the bootstrap script shut down.
+func applyTaskTemplate(task *api.Task, tmpl *api.Task) error {
+	// Provider env and config win, the template only adds keys
+	task.Env = mergeMap(task.Env, tmpl.Env, false)
+	for key, value := range tmpl.Config {
+		if key == "volumes" {
+			task.Config[key] = appendVolumes(task.Config[key], value)
+			continue
+		}
+		if _, exists := task.Config[key]; !exists {
+			task.Config[key] = value
+		}
+	}
+
+	task.Meta = mergeMap(task.Meta, tmpl.Meta, true)
+	task.Constraints = append(task.Constraints, tmpl.Constraints...)
+	task.Affinities = append(task.Affinities, tmpl.Affinities...)
+	task.Services = append(task.Services, tmpl.Services...)
+	task.VolumeMounts = append(task.VolumeMounts, tmpl.VolumeMounts...)
+	task.Templates = append(task.Templates, tmpl.Templates...)
+	task.Artifacts = append(task.Artifacts, tmpl.Artifacts...)
+	task.Identities = append(task.Identities, tmpl.Identities...)
+
+	if tmpl.RestartPolicy != nil {
+		task.RestartPolicy = tmpl.RestartPolicy
+	}
+	if tmpl.KillTimeout != nil {
+		if err := opts.ValidateKillTimeout("job template kill_timeout", *tmpl.KillTimeout); err != nil {
+			return err
+		}
+		task.KillTimeout = tmpl.KillTimeout
+	}
+	if tmpl.KillSignal != "" {
+		if err := opts.ValidateKillSignal("job template kill_signal", tmpl.KillSignal); err != nil {
+			return err
+		}
+		task.KillSignal = tmpl.KillSignal
+	}
+	if tmpl.ShutdownDelay != 0 {
+		task.ShutdownDelay = tmpl.ShutdownDelay
+	}
+	if tmpl.LogConfig != nil {
+		task.LogConfig = tmpl.LogConfig
+	}
+	return nil
+}
+
+// appendVolumes adds the template's Docker volumes to the provider's
+func appendVolumes(existing, extra interface{}) []string {
+	volumes, _ := existing.([]string)
+	switch extra := extra.(type) {
+	case []string:
+		volumes = append(volumes, extra...)
+	case []interface{}:
+		// Templates decoded from JSON
+		for _, v := range extra {
+			volumes = append(volumes, fmt.Sprint(v))
+		}
+	}
+	return volumes
+}
+
+// mergeMap adds the entries of extra to m. With override, extra's values replace
+// existing ones; otherwise existing values are kept.
+func mergeMap(m, extra map[string]string, override bool) map[string]string {
+	if len(extra) == 0 {
+		return m
+	}
+	if m == nil {
+		m = map[string]string{}
+	}
+	for k, v := range extra {
+		if _, exists := m[k]; exists && !override {
+			continue
+		}
+		m[k] = v
+	}
+	return m
+}
@@ -0,0 +1,305 @@
+package jobspec
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+	"github.com/hashicorp/nomad/api"
+)
+
+const jobTemplateJSON = `{
+  "Job": {
+    "ID": "ignored",
+    "Name": "ignored",
+    "Priority": 70,
+    "Meta": {"team": "ml"},
+    "Constraints": [{"LTarget": "${node.class}", "RTarget": "workspace", "Operand": "="}],
+    "TaskGroups": [{
+      "Name": "ignored",
+      "RestartPolicy": {"Attempts": 5, "Mode": "delay"},
+      "Volumes": {
+        "datasets": {"Name": "datasets", "Type": "host", "Source": "datasets", "ReadOnly": true}
+      },
+      "Tasks": [
+        {
+          "Name": "workspace",
+          "Env": {"HF_HOME": "/datasets/hf", "HOME": "/ignored"},
+          "Config": {"volumes": ["/etc/pki:/etc/pki:ro"], "image": "ignored:latest", "labels": [{"team": "ml"}]},
+          "VolumeMounts": [{"Volume": "datasets", "Destination": "/datasets"}],
+          "KillTimeout": 30000000000
+        },
+        {
+          "Name": "postgres",
+          "Driver": "docker",
+          "Config": {"image": "postgres:16"},
+          "Lifecycle": {"Hook": "prestart", "Sidecar": true}
+        }
+      ]
+    }]
+  }
+}`
+
+func TestParseJSONJobTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "wrapped", data: `{"Job": {"Priority": 70}}`},
+		{name: "bare", data: `{"Priority": 70}`},
+		{name: "invalid", data: `{"Job": `, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job, err := ParseJSONJobTemplate([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseJSONJobTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (job.Priority == nil || *job.Priority != 70) {
+				t.Errorf("Expected priority 70, got %v", job.Priority)
+			}
+		})
+	}
+}
+
+func TestApplyJobTemplate(t *testing.T) {
+	options := baseOptions()
+	options.StorageMode = opts.StorageModePersistent
+	options.DriverOpts = nil
+	job, err := Build(options)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	job.TaskGroups[0].Tasks[0].Env["HOME"] = "/root"
+
+	tmpl, err := ParseJSONJobTemplate([]byte(jobTemplateJSON))
+	if err != nil {
+		t.Fatalf("ParseJSONJobTemplate failed: %v", err)
+	}
+	if err := ApplyJobTemplate(job, tmpl); err != nil {
+		t.Fatalf("ApplyJobTemplate failed: %v", err)
+	}
+
+	// Identity stays with the provider, job settings come from the template
+	if *job.ID != "devpod-test" || *job.Name != "devpod-test" || *job.TaskGroups[0].Name != "devpod-test" {
+		t.Errorf("Expected provider job identity, got %s/%s/%s", *job.ID, *job.Name, *job.TaskGroups[0].Name)
+	}
+	if *job.Priority != 70 || job.Meta["team"] != "ml" {
+		t.Errorf("Expected template priority and meta, got %d %v", *job.Priority, job.Meta)
+	}
+	if len(job.Constraints) != 1 {
+		t.Errorf("Expected template constraint, got %v", job.Constraints)
+	}
+
+	group := job.TaskGroups[0]
+	if group.RestartPolicy == nil || *group.RestartPolicy.Attempts != 5 {
+		t.Errorf("Expected template restart policy, got %v", group.RestartPolicy)
+	}
+	if _, ok := group.Volumes[persistentVolumeName]; !ok {
+		t.Error("Expected the workspace volume to be kept")
+	}
+	if v, ok := group.Volumes["datasets"]; !ok || v.Type != "host" {
+		t.Errorf("Expected datasets host volume, got %v", group.Volumes)
+	}
+
+	if len(group.Tasks) != 2 || group.Tasks[1].Name != "postgres" {
+		t.Fatalf("Expected postgres task added after the workspace task, got %d tasks", len(group.Tasks))
+	}
+
+	task := group.Tasks[0]
+	if task.Name != "devpod-test" || task.Config["image"] != defaultImage {
+		t.Errorf("Expected provider task name and image, got %s %v", task.Name, task.Config["image"])
+	}
+	if task.Env["HF_HOME"] != "/datasets/hf" || task.Env["HOME"] != "/root" {
+		t.Errorf("Expected template env added without overriding provider env, got %v", task.Env)
+	}
+	volumes := task.Config["volumes"].([]string)
+	if volumes[len(volumes)-1] != "/etc/pki:/etc/pki:ro" || len(volumes) != 5 {
+		t.Errorf("Expected template volume appended, got %v", volumes)
+	}
+	if _, ok := task.Config["labels"]; !ok {
+		t.Error("Expected template config key added")
+	}
+	if len(task.VolumeMounts) != 2 || *task.VolumeMounts[1].Destination != "/datasets" {
+		t.Errorf("Expected datasets volume mount appended, got %v", task.VolumeMounts)
+	}
+	if task.KillTimeout == nil || *task.KillTimeout != 30*time.Second {
+		t.Errorf("Expected 30s kill timeout, got %v", task.KillTimeout)
+	}
+}
+
+func TestApplyJobTemplate_Invalid(t *testing.T) {
+	batch := "batch"
+	two := 2
+	oneSecond := time.Second
+
+	tests := []struct {
+		name string
+		tmpl *api.Job
+	}{
+		{
+			name: "batch job",
+			tmpl: &api.Job{Type: &batch},
+		},
+		{
+			name: "multiple groups",
+			tmpl: &api.Job{TaskGroups: []*api.TaskGroup{{}, {}}},
+		},
+		{
+			name: "group count",
+			tmpl: &api.Job{TaskGroups: []*api.TaskGroup{{Count: &two}}},
+		},
+		{
+			name: "workspace volume conflict",
+			tmpl: &api.Job{TaskGroups: []*api.TaskGroup{{
+				Volumes: map[string]*api.VolumeRequest{persistentVolumeName: {Name: persistentVolumeName, Type: "host"}},
+			}}},
+		},
+		{
+			name: "network mode conflict",
+			tmpl: &api.Job{TaskGroups: []*api.TaskGroup{{
+				Networks: []*api.NetworkResource{{Mode: "host"}},
+			}}},
+		},
+		{
+			name: "legacy disconnect fields next to a disconnect block",
+			tmpl: &api.Job{TaskGroups: []*api.TaskGroup{{
+				MaxClientDisconnect: &oneSecond,
+				Disconnect:          &api.DisconnectStrategy{LostAfter: &oneSecond},
+			}}},
+		},
+		{
+			name: "both legacy disconnect fields",
+			tmpl: &api.Job{TaskGroups: []*api.TaskGroup{{
+				MaxClientDisconnect:       &oneSecond,
+				StopAfterClientDisconnect: &oneSecond,
+			}}},
+		},
+		{
+			name: "untrapped kill signal",
+			tmpl: &api.Job{TaskGroups: []*api.TaskGroup{{
+				Tasks: []*api.Task{{Name: workspaceTaskAlias, KillSignal: "SIGKILL"}},
+			}}},
+		},
+		{
+			name: "kill timeout too short for the final sync",
+			tmpl: &api.Job{TaskGroups: []*api.TaskGroup{{
+				Tasks: []*api.Task{{Name: workspaceTaskAlias, KillTimeout: &oneSecond}},
+			}}},
+		},
+		{
+			name: "duplicate task",
+			tmpl: &api.Job{TaskGroups: []*api.TaskGroup{{
+				Tasks: []*api.Task{{Name: "sidecar"}, {Name: "sidecar"}},
+			}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := baseOptions()
+			options.StorageMode = opts.StorageModePersistent
+			job, err := Build(options)
+			if err != nil {
+				t.Fatalf("Build failed: %v", err)
+			}
+
+			if err := ApplyJobTemplate(job, tt.tmpl); err == nil {
+				t.Error("Expected error for invalid job template")
+			}
+		})
+	}
+}
+
+func TestApplyJobTemplate_Network(t *testing.T) {
+	options := baseOptions()
+	options.NetworkMode = opts.NetworkModeBridge
+	options.Ports = []opts.Port{{Label: "http", To: 3000}}
+	options.Sidecars = []opts.Sidecar{{Name: "redis", Image: "redis:7"}}
+	job, err := Build(options)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	tmpl := &api.Job{TaskGroups: []*api.TaskGroup{{
+		Networks: []*api.NetworkResource{{
+			Mode:          "bridge",
+			DynamicPorts:  []api.Port{{Label: "metrics", To: 9090}},
+			ReservedPorts: []api.Port{{Label: "debug", Value: 9229}},
+			DNS:           &api.DNSConfig{Servers: []string{"10.0.0.53"}},
+		}},
+	}}}
+	if err := ApplyJobTemplate(job, tmpl); err != nil {
+		t.Fatalf("ApplyJobTemplate failed: %v", err)
+	}
+
+	network := job.TaskGroups[0].Networks[0]
+	if network.Mode != "bridge" || len(network.DynamicPorts) != 2 || len(network.ReservedPorts) != 1 {
+		t.Errorf("Expected the template ports added to the bridge network, got %+v", network)
+	}
+	if network.DynamicPorts[0].Label != "http" || network.DNS == nil {
+		t.Errorf("Expected the workspace port kept and the template DNS, got %+v", network)
+	}
+
+	conflict := &api.Job{TaskGroups: []*api.TaskGroup{{
+		Networks: []*api.NetworkResource{{DynamicPorts: []api.Port{{Label: "http"}}}},
+	}}}
+	if err := ApplyJobTemplate(job, conflict); err == nil {
+		t.Error("Expected error for a template port reusing a workspace port label")
+	}
+}
+
+func TestApplyJobTemplate_NetworkPortsMappedWithoutMode(t *testing.T) {
+	options := baseOptions()
+	options.Ports = []opts.Port{{Label: "http", To: 3000}}
+	job, err := Build(options)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	tmpl := &api.Job{TaskGroups: []*api.TaskGroup{{
+		Networks: []*api.NetworkResource{{
+			DynamicPorts:  []api.Port{{Label: "metrics", To: 9090}},
+			ReservedPorts: []api.Port{{Label: "debug", Value: 9229}},
+		}},
+	}}}
+	if err := ApplyJobTemplate(job, tmpl); err != nil {
+		t.Fatalf("ApplyJobTemplate failed: %v", err)
+	}
+
+	// The driver only maps the ports listed in the task config into the container
+	ports, _ := job.TaskGroups[0].Tasks[0].Config["ports"].([]string)
+	if !slices.Equal(ports, []string{"http", "debug", "metrics"}) {
+		t.Errorf("Expected the template ports mapped into the workspace container, got %v", ports)
+	}
+}
+
+func TestApplyJobTemplate_LegacyDisconnect(t *testing.T) {
+	options := baseOptions()
+	options.DisconnectStopAfter = "10m"
+	job, err := Build(options)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	lostAfter := time.Hour
+	tmpl := &api.Job{TaskGroups: []*api.TaskGroup{{MaxClientDisconnect: &lostAfter}}}
+	if err := ApplyJobTemplate(job, tmpl); err != nil {
+		t.Fatalf("ApplyJobTemplate failed: %v", err)
+	}
+
+	group := job.TaskGroups[0]
+	if group.MaxClientDisconnect != nil || group.StopAfterClientDisconnect != nil {
+		t.Error("Expected no legacy disconnect fields next to the disconnect block")
+	}
+	disconnect := group.Disconnect
+	if disconnect.LostAfter == nil || *disconnect.LostAfter != time.Hour || disconnect.StopOnClientAfter != nil {
+		t.Errorf("Expected lost_after from the template replacing stop_on_client_after, got %+v", disconnect)
+	}
+	if disconnect.Reconcile == nil || *disconnect.Reconcile != api.ReconcileOptionKeepOriginal {
+		t.Errorf("Expected the workspace reconcile setting kept, got %+v", disconnect)
+	}
+}
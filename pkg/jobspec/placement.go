@@ -0,0 +1,50 @@
+package jobspec
+
+import (
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+	"github.com/hashicorp/nomad/api"
+)
+
+// buildJobConstraints translates the user-defined constraints into job constraints
+func buildJobConstraints(options *opts.Options) []*api.Constraint {
+	var constraints []*api.Constraint
+	for _, c := range options.Constraints {
+		constraints = append(constraints, api.NewConstraint(c.Attribute, placementOperator(c.Operator), c.Value))
+	}
+	return constraints
+}
+
+// buildJobAffinities translates the user-defined affinities into job affinities
+func buildJobAffinities(options *opts.Options) []*api.Affinity {
+	var affinities []*api.Affinity
+	for _, a := range options.Affinities {
+		affinities = append(affinities, api.NewAffinity(a.Attribute, placementOperator(a.Operator), a.Value, int8(a.Weight)))
+	}
+	return affinities
+}
+
+// buildJobSpreads translates the user-defined spreads into job spreads
+func buildJobSpreads(options *opts.Options) []*api.Spread {
+	var spreads []*api.Spread
+	for _, s := range options.Spreads {
+		spread := &api.Spread{Attribute: s.Attribute}
+		// Leave the weight unset so Nomad applies its default
+		if s.Weight > 0 {
+			weight := int8(s.Weight)
+			spread.Weight = &weight
+		}
+		for _, target := range s.Targets {
+			spread.SpreadTarget = append(spread.SpreadTarget, api.NewSpreadTarget(target.Value, uint8(target.Percent)))
+		}
+		spreads = append(spreads, spread)
+	}
+	return spreads
+}
+
+// placementOperator returns the operator of a constraint or affinity, defaulting to "="
+func placementOperator(operator string) string {
+	if operator == "" {
+		return "="
+	}
+	return operator
+}
@@ -0,0 +1,64 @@
+package jobspec
+
+import (
+	"testing"
+
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+)
+
+func TestBuildJobPlacement(t *testing.T) {
+	options := &opts.Options{
+		Constraints: []opts.Constraint{
+			{Attribute: "${node.class}", Value: "workspace"},
+			{Attribute: "${meta.role}", Operator: "!=", Value: "build-agent"},
+		},
+		Affinities: []opts.Affinity{
+			{Attribute: "${meta.rack}", Operator: "regexp", Value: "r1.*", Weight: -30},
+		},
+		Spreads: []opts.Spread{
+			{Attribute: "${node.datacenter}"},
+			{Attribute: "${meta.zone}", Weight: 80, Targets: []opts.SpreadTarget{{Value: "a", Percent: 70}, {Value: "b", Percent: 30}}},
+		},
+	}
+
+	constraints := buildJobConstraints(options)
+	if len(constraints) != 2 {
+		t.Fatalf("Expected 2 constraints, got %d", len(constraints))
+	}
+	if constraints[0].LTarget != "${node.class}" || constraints[0].Operand != "=" || constraints[0].RTarget != "workspace" {
+		t.Errorf("Unexpected first constraint: %v", *constraints[0])
+	}
+	if constraints[1].Operand != "!=" || constraints[1].RTarget != "build-agent" {
+		t.Errorf("Unexpected second constraint: %v", *constraints[1])
+	}
+
+	affinities := buildJobAffinities(options)
+	if len(affinities) != 1 {
+		t.Fatalf("Expected 1 affinity, got %d", len(affinities))
+	}
+	if affinities[0].Operand != "regexp" || affinities[0].Weight == nil || *affinities[0].Weight != -30 {
+		t.Errorf("Unexpected affinity: %v", *affinities[0])
+	}
+
+	spreads := buildJobSpreads(options)
+	if len(spreads) != 2 {
+		t.Fatalf("Expected 2 spreads, got %d", len(spreads))
+	}
+	if spreads[0].Weight != nil || len(spreads[0].SpreadTarget) != 0 {
+		t.Errorf("Expected default weight and no targets for the first spread, got %v", *spreads[0])
+	}
+	if spreads[1].Weight == nil || *spreads[1].Weight != 80 {
+		t.Errorf("Expected spread weight 80, got %v", spreads[1].Weight)
+	}
+	if len(spreads[1].SpreadTarget) != 2 || spreads[1].SpreadTarget[0].Value != "a" || spreads[1].SpreadTarget[0].Percent != 70 {
+		t.Errorf("Unexpected spread targets: %v", spreads[1].SpreadTarget)
+	}
+}
+
+func TestBuildJobPlacement_Empty(t *testing.T) {
+	options := &opts.Options{}
+
+	if buildJobConstraints(options) != nil || buildJobAffinities(options) != nil || buildJobSpreads(options) != nil {
+		t.Error("Expected no placement without user configuration")
+	}
+}
@@ -0,0 +1,166 @@
+package jobspec
+
+import (
+	"fmt"
+	"strings"
+
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+	"github.com/hashicorp/nomad/api"
+)
+
+// Registry of images without a registry host
+const dockerHubRegistry = "docker.io"
+
+// Hosts that also refer to Docker Hub
+var dockerHubAliases = map[string]bool{
+	"index.docker.io":      true,
+	"registry-1.docker.io": true,
+}
+
+// imageRegistry returns the registry host of an image reference, following Docker's
+// rule that the first path component is a host if it contains a dot or a port
+func imageRegistry(image string) string {
+	if _, rest, ok := strings.Cut(image, "://"); ok {
+		image = rest
+	}
+	host, _, ok := strings.Cut(image, "/")
+	if !ok || (!strings.ContainsAny(host, ".:") && host != "localhost") {
+		return dockerHubRegistry
+	}
+	return canonicalRegistry(host)
+}
+
+// canonicalRegistry maps Docker Hub's aliases to docker.io
+func canonicalRegistry(registry string) string {
+	registry = strings.ToLower(registry)
+	if dockerHubAliases[registry] {
+		return dockerHubRegistry
+	}
+	return registry
+}
+
+// findRegistryAuth returns the index and credentials for the registry of image, or
+// -1 if none are configured
+func findRegistryAuth(options *opts.Options, image string) (int, *opts.RegistryAuth) {
+	registry := imageRegistry(image)
+	for i := range options.RegistryAuth {
+		if canonicalRegistry(options.RegistryAuth[i].Registry) == registry {
+			return i, &options.RegistryAuth[i]
+		}
+	}
+	return -1, nil
+}
+
+// configureImagePull sets the pull behavior and registry credentials of a task
+// running image in job jobID. Credentials are rendered into environment variables
+// that the driver config references, so they never appear in the job: from Vault,
+// or from the job's Nomad Variable (RegistryVariable) for credentials set in the
+// provider options.
+func configureImagePull(task *api.Task, image string, jobID string, options *opts.Options) {
+	if options.ForcePull {
+		task.Config["force_pull"] = true
+	}
+
+	index, auth := findRegistryAuth(options, image)
+	if auth == nil {
+		return
+	}
+
+	prefix := fmt.Sprintf("DEVPOD_REGISTRY_%d_", index)
+	var tmpl string
+	if auth.VaultPath != "" {
+		tmpl = generateRegistryAuthTemplate(auth.VaultPath, prefix)
+		// A task only needing Vault for the credentials doesn't need to react to a
+		// new Vault token either
+		if task.Vault == nil {
+			noop := "noop"
+			task.Vault = buildTaskVault(options)
+			task.Vault.ChangeMode = &noop
+			task.Vault.ChangeSignal = nil
+		}
+	} else {
+		tmpl = generateRegistryVariableTemplate(RegistryVariablePath(jobID), index, prefix)
+	}
+
+	destPath := fmt.Sprintf("secrets/registry-%d.env", index)
+	// Credentials are only used when the image is pulled, so rotating them
+	// must not restart the task
+	changeMode := "noop"
+	task.Templates = append(task.Templates, &api.Template{
+		DestPath:     &destPath,
+		EmbeddedTmpl: &tmpl,
+		Envvars:      boolPtr(true),
+		ChangeMode:   &changeMode,
+	})
+
+	task.Config["auth"] = []map[string]interface{}{{
+		"username": "${" + prefix + "USERNAME}",
+		"password": "${" + prefix + "PASSWORD}",
+	}}
+	if auth.SoftFail {
+		task.Config["auth_soft_fail"] = true
+	}
+}
+
+// RegistryVariablePath returns the path of the Nomad Variable holding the registry
+// credentials of job jobID. Tasks of the job can read it without an ACL policy.
+func RegistryVariablePath(jobID string) string {
+	return "nomad/jobs/" + jobID
+}
+
+// RegistryVariable returns the Nomad Variable holding the registry credentials set
+// in the provider options, which must exist before job jobID is registered, or nil
+// if all credentials come from Vault
+func RegistryVariable(options *opts.Options, jobID string) *api.Variable {
+	items := api.VariableItems{}
+	for i, auth := range options.RegistryAuth {
+		if auth.VaultPath != "" {
+			continue
+		}
+		username, password := auth.Username, auth.Password
+		if auth.Token != "" {
+			password = auth.Token
+			if username == "" {
+				username = registryTokenUsername
+			}
+		}
+		items[registryVariableItem(i, "username")] = username
+		items[registryVariableItem(i, "password")] = password
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	return &api.Variable{
+		Namespace: options.Namespace,
+		Path:      RegistryVariablePath(jobID),
+		Items:     items,
+	}
+}
+
+// registryVariableItem returns the item of the registry Nomad Variable holding
+// field of the registry auth at index
+func registryVariableItem(index int, field string) string {
+	return fmt.Sprintf("registry_%d_%s", index, field)
+}
+
+// Username sent with an access token that has none. Registries authenticating by
+// token, such as GHCR and GitLab, ignore it.
+const registryTokenUsername = "token"
+
+// generateRegistryAuthTemplate creates the template rendering registry credentials
+// from a Vault KV v2 secret with username and password or token fields
+func generateRegistryAuthTemplate(path, prefix string) string {
+	return "{{- with secret \"" + path + "\" -}}\n" +
+		"export " + prefix + "USERNAME=\"{{ or .Data.data.username \"" + registryTokenUsername + "\" }}\"\n" +
+		"export " + prefix + "PASSWORD=\"{{ or .Data.data.password .Data.data.token }}\"\n" +
+		"{{- end }}\n"
+}
+
+// generateRegistryVariableTemplate creates the template rendering the credentials of
+// the registry auth at index from the job's Nomad Variable
+func generateRegistryVariableTemplate(path string, index int, prefix string) string {
+	return "{{- with nomadVar \"" + path + "\" -}}\n" +
+		"export " + prefix + "USERNAME=\"{{ index . \"" + registryVariableItem(index, "username") + "\" }}\"\n" +
+		"export " + prefix + "PASSWORD=\"{{ index . \"" + registryVariableItem(index, "password") + "\" }}\"\n" +
+		"{{- end }}\n"
+}
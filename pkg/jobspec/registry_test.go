@@ -0,0 +1,63 @@
+package jobspec
+
+import (
+	"reflect"
+	"testing"
+
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+	"github.com/hashicorp/nomad/api"
+)
+
+func TestImageRegistry(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{"ubuntu:22.04", "docker.io"},
+		{"acme/postgres:16", "docker.io"},
+		{"docker.io/library/ubuntu", "docker.io"},
+		{"index.docker.io/acme/dev", "docker.io"},
+		{"ghcr.io/acme/dev:latest", "ghcr.io"},
+		{"GHCR.io/acme/dev", "ghcr.io"},
+		{"registry.example.com:5000/team/dev", "registry.example.com:5000"},
+		{"localhost/dev", "localhost"},
+		{"docker://ghcr.io/acme/dev", "ghcr.io"},
+		{"docker://ubuntu:22.04", "docker.io"},
+	}
+	for _, tt := range tests {
+		if got := imageRegistry(tt.image); got != tt.want {
+			t.Errorf("imageRegistry(%q) = %q, want %q", tt.image, got, tt.want)
+		}
+	}
+}
+
+func TestRegistryVariable(t *testing.T) {
+	options := &opts.Options{
+		Namespace: "dev",
+		RegistryAuth: []opts.RegistryAuth{
+			{Registry: "ghcr.io", VaultPath: "secret/data/registry/ghcr"},
+			{Registry: "docker.io", Username: "acme", Password: "s3cr3t"},
+			{Registry: "registry.example.com", Token: "glpat-example"},
+		},
+	}
+
+	variable := RegistryVariable(options, "devpod-test")
+
+	if variable.Path != "nomad/jobs/devpod-test" || variable.Namespace != "dev" {
+		t.Errorf("Expected the job's variable in its namespace, got %s %s", variable.Namespace, variable.Path)
+	}
+	want := api.VariableItems{
+		"registry_1_username": "acme",
+		"registry_1_password": "s3cr3t",
+		"registry_2_username": registryTokenUsername,
+		"registry_2_password": "glpat-example",
+	}
+	if !reflect.DeepEqual(variable.Items, want) {
+		t.Errorf("RegistryVariable items = %v, want %v", variable.Items, want)
+	}
+
+	vaultOnly := &opts.Options{RegistryAuth: options.RegistryAuth[:1]}
+	if RegistryVariable(vaultOnly, "devpod-test") != nil {
+		t.Error("Expected no variable when all credentials come from Vault")
+	}
+}
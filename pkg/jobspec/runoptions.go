@@ -0,0 +1,99 @@
+package jobspec
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+	"github.com/hashicorp/nomad/api"
+	"github.com/loft-sh/devpod/pkg/devcontainer/config"
+)
+
+// Directory in the shared allocation directory that holds the devcontainer's named
+// volumes. Relative driver volumes resolve against the task directory, which the
+// driver allows even when host volumes are disabled on the client.
+const runVolumesDir = "../alloc/devpod-volumes"
+
+// Characters allowed in the directory name of a named volume
+var volumeNameUnsafe = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// runCommand returns the devcontainer's entrypoint followed by its command, which
+// the bootstrap script runs once the workspace is ready
+func runCommand(options *opts.Options) []string {
+	if options.DriverOpts == nil {
+		return nil
+	}
+
+	var command []string
+	if options.DriverOpts.Entrypoint != "" {
+		command = append(command, options.DriverOpts.Entrypoint)
+	}
+	return append(command, options.DriverOpts.Cmd...)
+}
+
+// buildRunVolumes returns the driver volumes for the devcontainer's workspace mount
+// and additional mounts. Bind mounts use the path on the Nomad client and named
+// volumes get a directory in the allocation, so they live as long as the workspace.
+func buildRunVolumes(options *opts.Options) []string {
+	var volumes []string
+	for i, m := range options.RunMounts() {
+		source := m.Source
+		if m.Type != opts.MountTypeBind {
+			source = path.Join(runVolumesDir, volumeDirName(m.Source, i))
+		}
+
+		volume := source + ":" + m.Target
+		if mountReadOnly(m) {
+			volume += ":ro"
+		}
+		volumes = append(volumes, volume)
+	}
+	return volumes
+}
+
+// volumeDirName returns the directory name of a named volume. Anonymous volumes
+// are named after their position in the mounts.
+func volumeDirName(name string, index int) string {
+	name = volumeNameUnsafe.ReplaceAllString(name, "-")
+	if strings.Trim(name, ".") == "" {
+		return fmt.Sprintf("volume-%d", index)
+	}
+	return name
+}
+
+// mountReadOnly reports whether the mount has Docker's readonly option
+func mountReadOnly(m *config.Mount) bool {
+	for _, o := range m.Other {
+		switch o {
+		case "readonly", "ro", "readonly=true", "ro=true":
+			return true
+		}
+	}
+	return false
+}
+
+// applyRunOptions adds the devcontainer's labels, capabilities and security
+// options to the workspace task's driver config
+func applyRunOptions(task *api.Task, options *opts.Options) {
+	runOptions := options.DriverOpts
+	if runOptions == nil {
+		return
+	}
+
+	if len(runOptions.Labels) > 0 {
+		labels := map[string]string{}
+		for _, label := range runOptions.Labels {
+			key, value, _ := strings.Cut(label, "=")
+			labels[key] = value
+		}
+		task.Config["labels"] = labels
+	}
+	if len(runOptions.CapAdd) > 0 {
+		task.Config["cap_add"] = runOptions.CapAdd
+	}
+	if len(runOptions.SecurityOpt) > 0 {
+		task.Config["security_opt"] = runOptions.SecurityOpt
+	}
+}
@@ -0,0 +1,35 @@
+package jobspec
+
+import (
+	"testing"
+
+	"github.com/loft-sh/devpod/pkg/devcontainer/config"
+)
+
+func TestVolumeDirName(t *testing.T) {
+	tests := []struct {
+		name  string
+		index int
+		want  string
+	}{
+		{"go-mod-cache", 0, "go-mod-cache"},
+		{"dind-var-lib-docker-${devcontainerId}", 1, "dind-var-lib-docker---devcontainerId-"},
+		{"../../etc", 2, "..-..-etc"},
+		{"..", 3, "volume-3"},
+		{"", 4, "volume-4"},
+	}
+	for _, tt := range tests {
+		if got := volumeDirName(tt.name, tt.index); got != tt.want {
+			t.Errorf("volumeDirName(%q, %d) = %q, want %q", tt.name, tt.index, got, tt.want)
+		}
+	}
+}
+
+func TestMountReadOnly(t *testing.T) {
+	if !mountReadOnly(&config.Mount{Other: []string{"consistency=cached", "readonly"}}) {
+		t.Error("Expected readonly mount")
+	}
+	if mountReadOnly(&config.Mount{Other: []string{"consistency=cached"}}) {
+		t.Error("Expected writable mount")
+	}
+}
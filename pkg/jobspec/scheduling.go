@@ -0,0 +1,59 @@
+package jobspec
+
+import (
+	"time"
+
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+	"github.com/hashicorp/nomad/api"
+)
+
+// configureScheduling sets the restart, reschedule and disconnect policies of the
+// group and how the workspace task is stopped
+func configureScheduling(group *api.TaskGroup, task *api.Task, options *opts.Options) {
+	restartAttempts := options.RestartAttempts
+	restartMode := "fail"
+	group.RestartPolicy = &api.RestartPolicy{
+		Attempts: &restartAttempts,
+		Interval: parseDuration(options.RestartInterval),
+		Delay:    parseDuration(options.RestartDelay),
+		Mode:     &restartMode,
+	}
+
+	// Without attempts and unlimited a failed workspace stays on its node
+	rescheduleAttempts := options.RescheduleAttempts
+	unlimited := false
+	group.ReschedulePolicy = &api.ReschedulePolicy{
+		Attempts:  &rescheduleAttempts,
+		Interval:  parseDuration(options.RescheduleInterval),
+		Unlimited: &unlimited,
+	}
+
+	// Keep the original workspace, which holds the user's data, when its client
+	// reconnects after a replacement was started
+	replace := options.DisconnectReplace
+	reconcile := api.ReconcileOptionKeepOriginal
+	group.Disconnect = &api.DisconnectStrategy{
+		LostAfter:         parseDuration(options.DisconnectLostAfter),
+		StopOnClientAfter: parseDuration(options.DisconnectStopAfter),
+		Replace:           &replace,
+		Reconcile:         &reconcile,
+	}
+
+	task.KillTimeout = parseDuration(options.KillTimeout)
+	if options.KillSignal != "" {
+		task.KillSignal = options.KillSignal
+	}
+}
+
+// parseDuration returns nil for unset durations. Durations are validated when
+// options are loaded.
+func parseDuration(s string) *time.Duration {
+	if s == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return nil
+	}
+	return &d
+}
@@ -0,0 +1,86 @@
+package jobspec
+
+import (
+	"fmt"
+	"strings"
+
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+	"github.com/hashicorp/nomad/api"
+)
+
+// Placeholders replaced in the user-defined service tags
+const (
+	tagPlaceholderJob   = "{job}"
+	tagPlaceholderOwner = "{owner}"
+	tagPlaceholderLabel = "{label}"
+)
+
+// buildServices registers every exposed port of the workspace as a service, tagged
+// for the ingress router in the options
+func buildServices(options *opts.Options) []*api.Service {
+	if options.ServiceProvider == "" {
+		return nil
+	}
+
+	var services []*api.Service
+	for i, p := range options.Ports {
+		name := options.ServiceName(p.Label)
+
+		tags := serviceTags(options, p.Label)
+		tags = append(tags, routingTags(options, name, previewHost(options, i, p.Label))...)
+
+		meta := map[string]string{"devpod_job": options.JobId}
+		if options.Owner != "" {
+			meta["devpod_owner"] = options.Owner
+		}
+
+		services = append(services, &api.Service{
+			Name:      name,
+			PortLabel: p.Label,
+			Provider:  options.ServiceProvider,
+			Tags:      tags,
+			Meta:      meta,
+		})
+	}
+	return services
+}
+
+// serviceTags returns the user-defined tags with the placeholders replaced
+func serviceTags(options *opts.Options, label string) []string {
+	replacer := strings.NewReplacer(
+		tagPlaceholderJob, options.JobId,
+		tagPlaceholderOwner, options.Owner,
+		tagPlaceholderLabel, label,
+	)
+
+	var tags []string
+	for _, tag := range options.ServiceTags {
+		tags = append(tags, replacer.Replace(tag))
+	}
+	return tags
+}
+
+// previewHost returns the hostname the router serves the port on: the first port
+// gets <job>.<domain>, the others <job>-<label>.<domain>
+func previewHost(options *opts.Options, index int, label string) string {
+	name := strings.ToLower(options.JobId)
+	if index > 0 {
+		name = options.ServiceName(label)
+	}
+	return name + "." + options.ServiceDomain
+}
+
+// routingTags returns the tags the ingress router discovers the service by
+func routingTags(options *opts.Options, name, host string) []string {
+	switch options.ServiceRouting {
+	case opts.ServiceRoutingTraefik:
+		return []string{
+			"traefik.enable=true",
+			fmt.Sprintf("traefik.http.routers.%s.rule=Host(`%s`)", name, host),
+			fmt.Sprintf("traefik.http.routers.%s.tls=true", name),
+		}
+	case opts.ServiceRoutingFabio:
+		return []string{"urlprefix-" + host + "/"}
+	}
+	return nil
+}
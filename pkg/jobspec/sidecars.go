@@ -0,0 +1,63 @@
+package jobspec
+
+import (
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+	"github.com/hashicorp/nomad/api"
+)
+
+const (
+	defaultSidecarCPU      = 100
+	defaultSidecarMemoryMB = 256
+)
+
+// buildSidecarTasks translates the user-defined sidecars into tasks. Sidecars run
+// for the lifetime of the workspace and share the group's network namespace.
+func buildSidecarTasks(options *opts.Options) []*api.Task {
+	taskDriver := workspaceDriver(options)
+
+	var tasks []*api.Task
+	for _, s := range options.Sidecars {
+		cpu := s.CPU
+		if cpu == 0 {
+			cpu = defaultSidecarCPU
+		}
+		mem := s.MemoryMB
+		if mem == 0 {
+			mem = defaultSidecarMemoryMB
+		}
+		hook := s.Lifecycle
+		if hook == "" {
+			hook = opts.SidecarLifecyclePrestart
+		}
+
+		config := map[string]interface{}{
+			"image": taskDriver.image(s.Image),
+		}
+		if s.Command != "" {
+			config["command"] = s.Command
+		}
+		if len(s.Args) > 0 {
+			config["args"] = s.Args
+		}
+
+		task := &api.Task{
+			Name:   s.Name,
+			Driver: taskDriver.name(),
+			Config: config,
+			Resources: &api.Resources{
+				CPU:      &cpu,
+				MemoryMB: &mem,
+			},
+			Lifecycle: &api.TaskLifecycle{
+				Hook:    hook,
+				Sidecar: true,
+			},
+		}
+		if len(s.Env) > 0 {
+			task.Env = s.Env
+		}
+		configureImagePull(task, s.Image, options.JobId, options)
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
@@ -0,0 +1,164 @@
+package jobspec
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+	"github.com/hashicorp/nomad/api"
+)
+
+// generateVaultTemplates creates Nomad template stanzas for Vault secrets. Each secret
+// may override the global change mode and signal and set its own splay.
+func generateVaultTemplates(secrets []opts.VaultSecret, changeMode, changeSignal string) []*api.Template {
+	if len(secrets) == 0 {
+		return nil
+	}
+
+	templates := make([]*api.Template, len(secrets))
+	for i, secret := range secrets {
+		tmpl := generateSecretTemplate(secret)
+		destPath := "secrets/vault-" + strconv.Itoa(i) + ".env"
+		secretChangeMode := changeMode
+		if secret.ChangeMode != "" {
+			secretChangeMode = secret.ChangeMode
+		}
+		secretChangeSignal := changeSignal
+		if secret.ChangeSignal != "" {
+			secretChangeSignal = secret.ChangeSignal
+		}
+		templates[i] = &api.Template{
+			DestPath:     &destPath,
+			EmbeddedTmpl: &tmpl,
+			Envvars:      boolPtr(true), // Makes secrets available as environment variables
+			ChangeMode:   &secretChangeMode,
+		}
+		// The bootstrap script traps this signal and refreshes the aggregated secrets
+		if secretChangeMode == "signal" {
+			templates[i].ChangeSignal = &secretChangeSignal
+		}
+		// Splay is validated when options are loaded
+		if splay, err := time.ParseDuration(secret.Splay); err == nil {
+			templates[i].Splay = &splay
+		}
+	}
+
+	return templates
+}
+
+// generateSecretTemplate creates a Nomad template string for a single Vault secret
+func generateSecretTemplate(secret opts.VaultSecret) string {
+	template := "{{- with secret \"" + secret.Path + "\" -}}\n"
+
+	// Sort fields so the rendered job is stable across runs
+	fields := make([]string, 0, len(secret.Fields))
+	for vaultField := range secret.Fields {
+		fields = append(fields, vaultField)
+	}
+	sort.Strings(fields)
+
+	for _, vaultField := range fields {
+		template += "export " + secret.Fields[vaultField] + "=\"{{ .Data.data." + vaultField + " }}\"\n"
+	}
+
+	template += "{{- end }}\n" // Don't strip trailing whitespace to preserve newlines
+	return template
+}
+
+// generateNomadVariableTemplates creates Nomad template stanzas for Nomad Variables.
+// They are rendered next to the Vault templates and aggregated into the same secrets file.
+func generateNomadVariableTemplates(variables []opts.NomadVariable, changeMode, changeSignal string) []*api.Template {
+	if len(variables) == 0 {
+		return nil
+	}
+
+	templates := make([]*api.Template, len(variables))
+	for i, variable := range variables {
+		tmpl := generateNomadVariableTemplate(variable)
+		destPath := "secrets/nomadvar-" + strconv.Itoa(i) + ".env"
+		templates[i] = &api.Template{
+			DestPath:     &destPath,
+			EmbeddedTmpl: &tmpl,
+			Envvars:      boolPtr(true),
+			ChangeMode:   &changeMode,
+		}
+		if changeMode == "signal" {
+			templates[i].ChangeSignal = &changeSignal
+		}
+	}
+
+	return templates
+}
+
+// generateNomadVariableTemplate creates a Nomad template string for a single Nomad Variable
+func generateNomadVariableTemplate(variable opts.NomadVariable) string {
+	template := "{{- with nomadVar \"" + variable.Path + "\" -}}\n"
+
+	// Sort items so the rendered job is stable across runs
+	items := make([]string, 0, len(variable.Fields))
+	for item := range variable.Fields {
+		items = append(items, item)
+	}
+	sort.Strings(items)
+
+	for _, item := range items {
+		// index works for item names that are not valid template identifiers
+		template += "export " + variable.Fields[item] + "=\"{{ index . \"" + item + "\" }}\"\n"
+	}
+
+	template += "{{- end }}\n"
+	return template
+}
+
+// generateConsulTemplate creates a single Nomad template stanza rendering Consul KV
+// values and service addresses as environment variables. It is aggregated into the
+// workspace secrets file together with the Vault and Nomad Variable templates.
+func generateConsulTemplate(kvs []opts.ConsulKV, services []opts.ConsulService, changeMode, changeSignal string) *api.Template {
+	tmpl := ""
+
+	for _, kv := range kvs {
+		if kv.Default != nil {
+			tmpl += "export " + kv.Env + "=\"{{ keyOrDefault " + strconv.Quote(kv.Key) + " " + strconv.Quote(*kv.Default) + " }}\"\n"
+		} else {
+			// key blocks rendering until the key exists
+			tmpl += "export " + kv.Env + "=\"{{ key " + strconv.Quote(kv.Key) + " }}\"\n"
+		}
+	}
+
+	for _, service := range services {
+		// Query syntax is [tag.]name[@datacenter]; the first healthy instance is used
+		query := service.Name
+		if service.Tag != "" {
+			query = service.Tag + "." + query
+		}
+		if service.Datacenter != "" {
+			query += "@" + service.Datacenter
+		}
+		lookup := "{{ with service " + strconv.Quote(query) + " }}{{ with index . 0 }}"
+		if service.AddressEnv != "" {
+			tmpl += "export " + service.AddressEnv + "=\"" + lookup + "{{ .Address }}{{ end }}{{ end }}\"\n"
+		}
+		if service.PortEnv != "" {
+			tmpl += "export " + service.PortEnv + "=\"" + lookup + "{{ .Port }}{{ end }}{{ end }}\"\n"
+		}
+	}
+
+	destPath := "secrets/consul.env"
+	template := &api.Template{
+		DestPath:     &destPath,
+		EmbeddedTmpl: &tmpl,
+		Envvars:      boolPtr(true),
+		ChangeMode:   &changeMode,
+	}
+	if changeMode == "signal" {
+		template.ChangeSignal = &changeSignal
+	}
+
+	return template
+}
+
+// boolPtr returns a pointer to a bool value
+func boolPtr(b bool) *bool {
+	return &b
+}
@@ -0,0 +1,105 @@
+package jobspec
+
+import (
+	"testing"
+	"time"
+
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+)
+
+func TestGenerateNomadVariableTemplates(t *testing.T) {
+	variables := []opts.NomadVariable{
+		{Path: "nomad/jobs/devpod/db", Fields: map[string]string{"user": "DB_USER", "pass-word": "DB_PASSWORD"}},
+	}
+
+	templates := generateNomadVariableTemplates(variables, "noop", "SIGHUP")
+
+	if len(templates) != 1 {
+		t.Fatalf("Expected 1 template, got %d", len(templates))
+	}
+	if *templates[0].DestPath != "secrets/nomadvar-0.env" {
+		t.Errorf("Expected dest path secrets/nomadvar-0.env, got %q", *templates[0].DestPath)
+	}
+	if *templates[0].ChangeMode != "noop" {
+		t.Errorf("Expected change mode noop, got %q", *templates[0].ChangeMode)
+	}
+
+	expected := "{{- with nomadVar \"nomad/jobs/devpod/db\" -}}\n" +
+		"export DB_PASSWORD=\"{{ index . \"pass-word\" }}\"\n" +
+		"export DB_USER=\"{{ index . \"user\" }}\"\n" +
+		"{{- end }}\n"
+	if *templates[0].EmbeddedTmpl != expected {
+		t.Errorf("Unexpected template:\n%s\nexpected:\n%s", *templates[0].EmbeddedTmpl, expected)
+	}
+}
+
+func TestGenerateConsulTemplate(t *testing.T) {
+	emptyDefault := ""
+	kvs := []opts.ConsulKV{
+		{Key: "dev/postgres/database", Env: "DB_NAME"},
+		{Key: "dev/feature/flag", Env: "FEATURE_FLAG", Default: &emptyDefault},
+	}
+	services := []opts.ConsulService{
+		{Name: "postgres", AddressEnv: "DB_HOST", PortEnv: "DB_PORT"},
+		{Name: "redis", Tag: "primary", Datacenter: "dc2", AddressEnv: "REDIS_HOST"},
+	}
+
+	template := generateConsulTemplate(kvs, services, "restart", "SIGHUP")
+
+	if *template.DestPath != "secrets/consul.env" {
+		t.Errorf("Expected dest path secrets/consul.env, got %q", *template.DestPath)
+	}
+	if *template.ChangeMode != "restart" || template.ChangeSignal != nil {
+		t.Errorf("Unexpected change mode %q / signal %v", *template.ChangeMode, template.ChangeSignal)
+	}
+
+	expected := "export DB_NAME=\"{{ key \"dev/postgres/database\" }}\"\n" +
+		"export FEATURE_FLAG=\"{{ keyOrDefault \"dev/feature/flag\" \"\" }}\"\n" +
+		"export DB_HOST=\"{{ with service \"postgres\" }}{{ with index . 0 }}{{ .Address }}{{ end }}{{ end }}\"\n" +
+		"export DB_PORT=\"{{ with service \"postgres\" }}{{ with index . 0 }}{{ .Port }}{{ end }}{{ end }}\"\n" +
+		"export REDIS_HOST=\"{{ with service \"primary.redis@dc2\" }}{{ with index . 0 }}{{ .Address }}{{ end }}{{ end }}\"\n"
+	if *template.EmbeddedTmpl != expected {
+		t.Errorf("Unexpected template:\n%s\nexpected:\n%s", *template.EmbeddedTmpl, expected)
+	}
+}
+
+func TestGenerateVaultTemplates_SignalModeSetsChangeSignal(t *testing.T) {
+	secrets := []opts.VaultSecret{{Path: "secret/data/test", Fields: map[string]string{"key": "KEY"}}}
+
+	templates := generateVaultTemplates(secrets, "signal", "SIGHUP")
+
+	if len(templates) != 1 {
+		t.Fatalf("Expected 1 template, got %d", len(templates))
+	}
+	if templates[0].ChangeSignal == nil || *templates[0].ChangeSignal != "SIGHUP" {
+		t.Errorf("Expected change signal SIGHUP, got %v", templates[0].ChangeSignal)
+	}
+
+	templates = generateVaultTemplates(secrets, "restart", "SIGHUP")
+	if templates[0].ChangeSignal != nil {
+		t.Errorf("Expected no change signal in restart mode, got %q", *templates[0].ChangeSignal)
+	}
+}
+
+func TestGenerateVaultTemplates_PerSecretOverrides(t *testing.T) {
+	secrets := []opts.VaultSecret{
+		{Path: "secret/data/db", Fields: map[string]string{"password": "DB_PASSWORD"}},
+		{Path: "secret/data/api", Fields: map[string]string{"key": "API_KEY"}, ChangeMode: "signal", ChangeSignal: "SIGUSR2", Splay: "30s"},
+	}
+
+	templates := generateVaultTemplates(secrets, "restart", "SIGHUP")
+
+	if *templates[0].ChangeMode != "restart" || templates[0].ChangeSignal != nil || templates[0].Splay != nil {
+		t.Errorf("Expected global settings for the first secret, got mode %q signal %v splay %v",
+			*templates[0].ChangeMode, templates[0].ChangeSignal, templates[0].Splay)
+	}
+	if *templates[1].ChangeMode != "signal" {
+		t.Errorf("Expected change mode signal, got %q", *templates[1].ChangeMode)
+	}
+	if templates[1].ChangeSignal == nil || *templates[1].ChangeSignal != "SIGUSR2" {
+		t.Errorf("Expected change signal SIGUSR2, got %v", templates[1].ChangeSignal)
+	}
+	if templates[1].Splay == nil || *templates[1].Splay != 30*time.Second {
+		t.Errorf("Expected splay 30s, got %v", templates[1].Splay)
+	}
+}
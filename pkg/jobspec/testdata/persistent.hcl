@@ -0,0 +1,62 @@
+job "devpod-test" {
+  name = "devpod-test"
+  priority = 50
+
+  group "devpod-test" {
+    task "devpod-test" {
+      driver = "docker"
+      user = "root"
+      kill_timeout = "30s"
+      kill_signal = "SIGTERM"
+
+      config {
+        args = ["/bin/sh", "-c", "mkdir -p /tmp/devpod-workspaces/devpod-test /persistent\n\n# Install dependencies (rsync for efficient syncing)\napt-get update -qq && apt-get install -y -qq curl git ca-certificates rsync && update-ca-certificates || exit 1\n\n# Restore from persistent storage if it has data\nif [ -d /persistent/agent ] && [ \"$(ls -A /persistent/agent 2>/dev/null)\" ]; then\n  echo \"Restoring workspace from persistent storage...\"\n  rsync -a /persistent/ /tmp/devpod-workspaces/devpod-test/\nfi\n\n# Combine rendered secret templates into the shared secrets file\nrefresh_secrets() {\n  found=\"\"\n  for f in /secrets/vault-*.env /secrets/nomadvar-*.env /secrets/consul.env; do\n    [ -f \"$f\" ] || continue\n    [ -n \"$found\" ] || : > /tmp/devpod-workspaces/devpod-test/.vault-secrets.tmp\n    cat \"$f\" >> /tmp/devpod-workspaces/devpod-test/.vault-secrets.tmp\n    found=1\n  done\n  [ -n \"$found\" ] || return 0\n  chmod 644 /tmp/devpod-workspaces/devpod-test/.vault-secrets.tmp\n  if cmp -s /tmp/devpod-workspaces/devpod-test/.vault-secrets.tmp /tmp/devpod-workspaces/devpod-test/.vault-secrets; then\n    rm -f /tmp/devpod-workspaces/devpod-test/.vault-secrets.tmp\n  else\n    mv -f /tmp/devpod-workspaces/devpod-test/.vault-secrets.tmp /tmp/devpod-workspaces/devpod-test/.vault-secrets\n  fi\n}\n\n# Copy the shared secrets file into every workspace content directory\nsync_workspace_secrets() {\n  [ -f /tmp/devpod-workspaces/devpod-test/.vault-secrets ] || return 0\n  find /tmp/devpod-workspaces/devpod-test/agent/contexts/*/workspaces/*/content -maxdepth 0 -type d 2>/dev/null | while read wsdir; do\n    if ! cmp -s /tmp/devpod-workspaces/devpod-test/.vault-secrets \"$wsdir/.vault-secrets\"; then\n      cp /tmp/devpod-workspaces/devpod-test/.vault-secrets \"$wsdir/.vault-secrets.tmp\" && chmod 644 \"$wsdir/.vault-secrets.tmp\" && mv -f \"$wsdir/.vault-secrets.tmp\" \"$wsdir/.vault-secrets\"\n    fi\n  done\n}\n\n# Stop the workspace command and background processes, then flush the workspace to persistent storage, when Nomad stops the task\nshutdown() {\n  trap '' TERM INT\n  kill \"$command_pid\" 2>/dev/null\n  wait \"$command_pid\" 2>/dev/null\n  kill $bg_pids 2>/dev/null\n  wait $bg_pids 2>/dev/null\n  echo \"Syncing to persistent storage...\"\n  rsync -a --delete /tmp/devpod-workspaces/devpod-test/ /persistent/\n  exit \"$${1:-0}\"\n}\ntrap shutdown TERM INT\n\nrefresh_secrets\n\n# Mark as ready\nsleep 2 && touch /tmp/.devpod-ready\n\n# Background process: copy secrets to workspace content directories\n(trap 'kill $sleep_pid 2>/dev/null; exit 0' TERM\nwhile true; do\n  sync_workspace_secrets\n  sleep 5 & sleep_pid=$!; wait $sleep_pid\ndone) &\nbg_pids=\"$bg_pids $!\"\n\n# Background process: sync to persistent storage every 60 seconds\n(trap 'kill $sleep_pid 2>/dev/null; exit 0' TERM\nwhile true; do\n  sleep 60 & sleep_pid=$!; wait $sleep_pid\n  rsync -a --delete /tmp/devpod-workspaces/devpod-test/ /persistent/ 2>/dev/null || true\ndone) &\nbg_pids=\"$bg_pids $!\"\n\n# Run the workspace command, or keep the container running without one\n[ \"$#\" -gt 0 ] || set -- sleep infinity\n\"$@\" &\ncommand_pid=$!\nwhile kill -0 $command_pid 2>/dev/null; do wait $command_pid; status=$?; done\nshutdown \"$status\"\n"]
+        image = "ubuntu:22.04"
+        init = true
+        network_mode = "bridge"
+        privileged = true
+        volumes = ["/var/run/docker.sock:/var/run/docker.sock", "/etc/docker/certs.d:/etc/docker/certs.d:ro", "/usr/local/share/ca-certificates/registry.cluster.crt:/usr/local/share/ca-certificates/registry.cluster.crt:ro", "/tmp/devpod-workspaces/devpod-test:/tmp/devpod-workspaces/devpod-test"]
+      }
+
+      resources {
+        cpu = 200
+        memory = 512
+      }
+
+      volume_mount {
+        volume = "workspace"
+        destination = "/persistent"
+        read_only = false
+      }
+    }
+
+    volume "workspace" {
+      type = "csi"
+      source = "devpod-devpod-test"
+      access_mode = "single-node-writer"
+      attachment_mode = "file-system"
+
+      mount_options {
+        fs_type = "ext4"
+      }
+    }
+
+    restart {
+      interval = "30m0s"
+      attempts = 2
+      delay = "15s"
+      mode = "fail"
+    }
+
+    disconnect {
+      replace = false
+      reconcile = "keep_original"
+    }
+
+    reschedule {
+      attempts = 0
+      interval = "1h0m0s"
+      unlimited = false
+    }
+  }
+}
@@ -0,0 +1,55 @@
+package jobspec
+
+import (
+	"fmt"
+	"strconv"
+
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+	"github.com/hashicorp/nomad/api"
+)
+
+// BuildCSIVolume returns the CSI volume that backs the workspace in persistent
+// storage mode. Ceph credentials are not part of the spec; the caller adds them
+// from Vault when creating the volume.
+func BuildCSIVolume(options *opts.Options) (*api.CSIVolume, error) {
+	disk, err := strconv.Atoi(options.DiskMB)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NOMAD_DISKMB %q: %w", options.DiskMB, err)
+	}
+
+	// Convert MB to bytes for CSI volume capacity
+	capacityBytes := int64(disk) * 1024 * 1024
+	volumeID := options.GetVolumeID()
+
+	return &api.CSIVolume{
+		ID:        volumeID,
+		Name:      volumeID,
+		Namespace: options.Namespace,
+		PluginID:  options.CSIPluginID,
+
+		RequestedCapacityMin: capacityBytes,
+		RequestedCapacityMax: capacityBytes,
+
+		AccessMode:     api.CSIVolumeAccessModeSingleNodeWriter,
+		AttachmentMode: api.CSIVolumeAttachmentModeFilesystem,
+
+		MountOptions: &api.CSIMountOptions{
+			FSType: "ext4",
+		},
+
+		RequestedCapabilities: []*api.CSIVolumeCapability{
+			{
+				AccessMode:     api.CSIVolumeAccessModeSingleNodeWriter,
+				AttachmentMode: api.CSIVolumeAttachmentModeFilesystem,
+			},
+		},
+
+		// Ceph-CSI specific parameters
+		Parameters: map[string]string{
+			"clusterID":                 options.CSIClusterID,
+			"pool":                      options.CSIPool,
+			"csi.storage.k8s.io/fstype": "ext4",
+			"imageFeatures":             "layering",
+		},
+	}, nil
+}
@@ -0,0 +1,51 @@
+package jobspec
+
+import (
+	"testing"
+
+	opts "github.com/briancain/devpod-provider-nomad/pkg/options"
+)
+
+func TestBuildCSIVolume(t *testing.T) {
+	options := baseOptions()
+	options.StorageMode = opts.StorageModePersistent
+	options.Namespace = "dev"
+	options.CSIClusterID = "cluster-1"
+	options.DiskMB = "10240"
+
+	vol, err := BuildCSIVolume(options)
+	if err != nil {
+		t.Fatalf("BuildCSIVolume failed: %v", err)
+	}
+
+	if vol.ID != "devpod-devpod-test" || vol.Name != vol.ID || vol.Namespace != "dev" {
+		t.Errorf("Unexpected volume identity: id %s name %s namespace %s", vol.ID, vol.Name, vol.Namespace)
+	}
+	if vol.RequestedCapacityMin != 10240*1024*1024 || vol.RequestedCapacityMax != vol.RequestedCapacityMin {
+		t.Errorf("Expected 10GiB capacity, got %d-%d", vol.RequestedCapacityMin, vol.RequestedCapacityMax)
+	}
+	if vol.Parameters["clusterID"] != "cluster-1" || vol.Parameters["pool"] != "nomad" {
+		t.Errorf("Unexpected Ceph parameters: %v", vol.Parameters)
+	}
+	if vol.Secrets != nil {
+		t.Error("Expected no secrets in the volume spec")
+	}
+
+	// The job must request the same volume
+	job, err := Build(options)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if source := job.TaskGroups[0].Volumes[persistentVolumeName].Source; source != vol.ID {
+		t.Errorf("Job requests volume %s, expected %s", source, vol.ID)
+	}
+}
+
+func TestBuildCSIVolume_InvalidDisk(t *testing.T) {
+	options := baseOptions()
+	options.DiskMB = "10G"
+
+	if _, err := BuildCSIVolume(options); err == nil {
+		t.Error("Expected error for invalid disk size")
+	}
+}
@@ -2,10 +2,13 @@ package nomad
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
@@ -45,6 +48,25 @@ func (n *Nomad) Init(
 	return nil
 }
 
+// NodePoolExists checks if a node pool exists
+func (n *Nomad) NodePoolExists(ctx context.Context, name string) (bool, error) {
+	_, _, err := n.client.NodePools().Info(name, (&api.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// isNotFound reports whether the Nomad API answered a request with 404 Not Found
+func isNotFound(err error) bool {
+	var respErr api.UnexpectedResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode() == http.StatusNotFound
+}
+
 func (n *Nomad) Create(
 	ctx context.Context,
 	job *api.Job,
@@ -57,6 +79,37 @@ func (n *Nomad) Create(
 	return resp, nil
 }
 
+// ParseJobHCL converts an HCL jobspec to a job using the Nomad server's parser
+func (n *Nomad) ParseJobHCL(
+	ctx context.Context,
+	jobHCL string,
+) (*api.Job, error) {
+	// Jobs().ParseHCLOpts doesn't take request options, so the request is made
+	// directly to honor ctx
+	var job api.Job
+	_, err := n.client.Raw().Write("/v1/jobs/parse", &api.JobsParseRequest{
+		JobHCL: jobHCL,
+	}, &job, (&api.WriteOptions{}).WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Plan runs the scheduler against the job without registering it and returns
+// the diff against the running job along with any placement failures
+func (n *Nomad) Plan(
+	ctx context.Context,
+	job *api.Job,
+) (*api.JobPlanResponse, error) {
+	resp, _, err := n.client.Jobs().Plan(job, true, (&api.WriteOptions{}).WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
 func (n *Nomad) Delete(
 	ctx context.Context,
 	jobID string,
@@ -69,6 +122,129 @@ func (n *Nomad) Delete(
 	return nil
 }
 
+// Stop stops the job and waits until none of its allocations is running, so the
+// workspace has finished its final sync. It returns the nodes the allocations were
+// placed on. The job is kept until Delete purges it.
+func (n *Nomad) Stop(
+	ctx context.Context,
+	jobID string,
+	timeout time.Duration,
+) ([]*api.Node, error) {
+	if _, _, err := n.client.Jobs().Deregister(jobID, false, nil); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		allocs, _, err := n.client.Jobs().Allocations(jobID, true, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		stopped := true
+		var nodeIDs []string
+		for _, alloc := range allocs {
+			switch alloc.ClientStatus {
+			case api.AllocClientStatusPending, api.AllocClientStatusRunning:
+				stopped = false
+			case api.AllocClientStatusLost:
+				// The node is gone, nothing can run there
+			default:
+				if !slices.Contains(nodeIDs, alloc.NodeID) {
+					nodeIDs = append(nodeIDs, alloc.NodeID)
+				}
+			}
+		}
+		if stopped {
+			nodes := make([]*api.Node, 0, len(nodeIDs))
+			for _, nodeID := range nodeIDs {
+				node, _, err := n.client.Nodes().Info(nodeID, nil)
+				if err != nil {
+					return nil, err
+				}
+				nodes = append(nodes, node)
+			}
+			return nodes, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timeout waiting for job %q to stop", jobID)
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunBatchJob registers a batch job, waits for its task to finish and returns the
+// task's stdout. The job is purged when it finishes, fails or the timeout expires.
+func (n *Nomad) RunBatchJob(
+	ctx context.Context,
+	job *api.Job,
+	taskName string,
+	timeout time.Duration,
+) (string, error) {
+	if _, _, err := n.client.Jobs().Register(job, nil); err != nil {
+		return "", err
+	}
+	defer func() {
+		_, _, _ = n.client.Jobs().Deregister(*job.ID, true, nil)
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("timeout waiting for job %q to finish", *job.ID)
+		case <-ticker.C:
+		}
+
+		allocs, _, err := n.client.Jobs().Allocations(*job.ID, false, nil)
+		if err != nil {
+			return "", err
+		}
+		for _, allocStub := range allocs {
+			if allocStub.ClientStatus != api.AllocClientStatusComplete && allocStub.ClientStatus != api.AllocClientStatusFailed {
+				continue
+			}
+			alloc, _, err := n.client.Allocations().Info(allocStub.ID, nil)
+			if err != nil {
+				return "", err
+			}
+
+			// A task that never started, e.g. because its image can't be pulled,
+			// has no output to read
+			state := alloc.TaskStates[taskName]
+			if state == nil || state.StartedAt.IsZero() {
+				return "", fmt.Errorf("task %q did not start: %s", taskName, lastTaskEvent(state))
+			}
+
+			cancelLogs := make(chan struct{})
+			frames, errCh := n.client.AllocFS().Logs(alloc, false, taskName, "stdout", "start", 0, cancelLogs, nil)
+			out, err := io.ReadAll(api.NewFrameReader(frames, errCh, cancelLogs))
+			if err != nil {
+				return "", fmt.Errorf("read logs of task %q: %w", taskName, err)
+			}
+			return string(out), nil
+		}
+	}
+}
+
+// lastTaskEvent returns the message of the task's most recent event
+func lastTaskEvent(state *api.TaskState) string {
+	if state == nil || len(state.Events) == 0 {
+		return "no task events"
+	}
+	return state.Events[len(state.Events)-1].DisplayMessage
+}
+
 func (n *Nomad) Status(
 	ctx context.Context,
 	jobID string,
@@ -95,8 +271,34 @@ func (n *Nomad) Status(
 	default:
 		return client.StatusNotFound, job, nil
 	}
+}
+
+// Ports returns the host ports allocated to the job's running allocation. A job
+// without a running allocation has no ports.
+func (n *Nomad) Ports(
+	ctx context.Context,
+	jobID string,
+) ([]api.PortMapping, error) {
+	allocs, _, err := n.client.Jobs().Allocations(jobID, false, nil)
+	if err != nil {
+		return nil, err
+	}
 
-	return client.StatusNotFound, job, nil
+	for _, allocStub := range allocs {
+		if allocStub.ClientStatus != "running" {
+			continue
+		}
+		alloc, _, err := n.client.Allocations().Info(allocStub.ID, nil)
+		if err != nil {
+			return nil, err
+		}
+		if alloc.AllocatedResources == nil {
+			return nil, nil
+		}
+		return alloc.AllocatedResources.Shared.Ports, nil
+	}
+
+	return nil, nil
 }
 
 // waitForHealthyAllocation polls until a healthy, running allocation is found for the job
@@ -223,6 +425,20 @@ func (n *Nomad) CommandDevContainer(
 		stdin, stdout, stderr, sizeCh, nil)
 }
 
+// PutVariable creates or replaces a Nomad Variable
+func (n *Nomad) PutVariable(ctx context.Context, variable *api.Variable) error {
+	writeOpts := (&api.WriteOptions{Namespace: variable.Namespace}).WithContext(ctx)
+	_, _, err := n.client.Variables().Create(variable, writeOpts)
+	return err
+}
+
+// DeleteVariable deletes a Nomad Variable
+func (n *Nomad) DeleteVariable(ctx context.Context, path string, namespace string) error {
+	writeOpts := (&api.WriteOptions{Namespace: namespace}).WithContext(ctx)
+	_, err := n.client.Variables().Delete(path, writeOpts)
+	return err
+}
+
 // VolumeExists checks if a CSI volume exists
 func (n *Nomad) VolumeExists(ctx context.Context, volumeID string, namespace string) (bool, error) {
 	logger := log.Default.ErrorStreamOnly()
@@ -253,48 +469,11 @@ type CSISecrets struct {
 // CreateCSIVolume creates a new CSI volume for a DevPod workspace
 func (n *Nomad) CreateCSIVolume(
 	ctx context.Context,
-	volumeID string,
-	capacityBytes int64,
-	pluginID string,
-	clusterID string,
-	pool string,
-	namespace string,
+	vol *api.CSIVolume,
 	secrets *CSISecrets,
 ) error {
 	logger := log.Default.ErrorStreamOnly()
-	logger.Infof("Creating CSI volume %s with capacity %d bytes", volumeID, capacityBytes)
-
-	vol := &api.CSIVolume{
-		ID:        volumeID,
-		Name:      volumeID,
-		Namespace: namespace,
-		PluginID:  pluginID,
-
-		RequestedCapacityMin: capacityBytes,
-		RequestedCapacityMax: capacityBytes,
-
-		AccessMode:     api.CSIVolumeAccessModeSingleNodeWriter,
-		AttachmentMode: api.CSIVolumeAttachmentModeFilesystem,
-
-		MountOptions: &api.CSIMountOptions{
-			FSType: "ext4",
-		},
-
-		RequestedCapabilities: []*api.CSIVolumeCapability{
-			{
-				AccessMode:     api.CSIVolumeAccessModeSingleNodeWriter,
-				AttachmentMode: api.CSIVolumeAttachmentModeFilesystem,
-			},
-		},
-
-		// Ceph-CSI specific parameters
-		Parameters: map[string]string{
-			"clusterID":                 clusterID,
-			"pool":                      pool,
-			"csi.storage.k8s.io/fstype": "ext4",
-			"imageFeatures":             "layering",
-		},
-	}
+	logger.Infof("Creating CSI volume %s with capacity %d bytes", vol.ID, vol.RequestedCapacityMin)
 
 	// Add CSI secrets for Ceph authentication
 	if secrets != nil {
@@ -305,15 +484,15 @@ func (n *Nomad) CreateCSIVolume(
 	}
 
 	writeOpts := &api.WriteOptions{
-		Namespace: namespace,
+		Namespace: vol.Namespace,
 	}
 
 	_, _, err := n.client.CSIVolumes().Create(vol, writeOpts)
 	if err != nil {
-		return fmt.Errorf("failed to create CSI volume %s: %w", volumeID, err)
+		return fmt.Errorf("failed to create CSI volume %s: %w", vol.ID, err)
 	}
 
-	logger.Infof("Successfully created CSI volume %s", volumeID)
+	logger.Infof("Successfully created CSI volume %s", vol.ID)
 	return nil
 }
 
@@ -0,0 +1,52 @@
+package nomad
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// testNomad returns a Nomad whose client talks to handler
+func testNomad(t *testing.T, handler http.HandlerFunc) *Nomad {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := api.NewClient(&api.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Nomad{client: client}
+}
+
+func TestNodePoolExists(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    bool
+		wantErr bool
+	}{
+		{"exists", http.StatusOK, `{"Name": "workspaces"}`, true, false},
+		{"missing", http.StatusNotFound, "node pool not found", false, false},
+		{"forbidden", http.StatusForbidden, "Permission denied: node pool not found in token policy", false, true},
+		{"proxy error", http.StatusBadGateway, "upstream not found", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := testNomad(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			})
+
+			got, err := n.NodePoolExists(context.Background(), "workspaces")
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("NodePoolExists() = %v, %v, want %v (error %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
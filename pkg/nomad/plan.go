@@ -0,0 +1,147 @@
+package nomad
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// FormatPlan returns a readable summary of a job plan: the diff against the
+// registered job, then whether the scheduler could place every allocation
+func FormatPlan(resp *api.JobPlanResponse) string {
+	var b strings.Builder
+
+	if resp.Diff != nil {
+		writeJobDiff(&b, resp.Diff)
+	}
+
+	b.WriteString("\nScheduler dry-run:\n")
+	if len(resp.FailedTGAllocs) == 0 {
+		b.WriteString("- All tasks successfully allocated.\n")
+	} else {
+		b.WriteString("- WARNING: Failed to place all allocations.\n")
+		for _, group := range sortedKeys(resp.FailedTGAllocs) {
+			writePlacementFailure(&b, group, resp.FailedTGAllocs[group])
+		}
+	}
+
+	if resp.Annotations != nil && len(resp.Annotations.PreemptedAllocs) > 0 {
+		fmt.Fprintf(&b, "\nPreemptions: %d allocation(s) would be preempted\n", len(resp.Annotations.PreemptedAllocs))
+		for _, alloc := range resp.Annotations.PreemptedAllocs {
+			fmt.Fprintf(&b, "  %s (job %q, task group %q)\n", alloc.ID, alloc.JobID, alloc.TaskGroup)
+		}
+	}
+
+	if resp.Warnings != "" {
+		fmt.Fprintf(&b, "\nWarnings:\n%s\n", strings.TrimSpace(resp.Warnings))
+	}
+
+	return b.String()
+}
+
+func writeJobDiff(b *strings.Builder, diff *api.JobDiff) {
+	fmt.Fprintf(b, "%s Job: %q\n", diffMarker(diff.Type), diff.ID)
+	writeFieldDiffs(b, diff.Fields, 1)
+	writeObjectDiffs(b, diff.Objects, 1)
+
+	for _, group := range diff.TaskGroups {
+		fmt.Fprintf(b, "%s Task Group: %q%s\n", diffMarker(group.Type), group.Name, formatUpdates(group.Updates))
+		writeFieldDiffs(b, group.Fields, 2)
+		writeObjectDiffs(b, group.Objects, 2)
+
+		for _, task := range group.Tasks {
+			annotations := ""
+			if len(task.Annotations) > 0 {
+				annotations = " (" + strings.Join(task.Annotations, ", ") + ")"
+			}
+			fmt.Fprintf(b, "  %s Task: %q%s\n", diffMarker(task.Type), task.Name, annotations)
+			writeFieldDiffs(b, task.Fields, 3)
+			writeObjectDiffs(b, task.Objects, 3)
+		}
+	}
+}
+
+func writeFieldDiffs(b *strings.Builder, fields []*api.FieldDiff, depth int) {
+	pad := strings.Repeat("  ", depth)
+	for _, field := range fields {
+		switch field.Type {
+		case "Added":
+			fmt.Fprintf(b, "%s+ %s: %q\n", pad, field.Name, field.New)
+		case "Deleted":
+			fmt.Fprintf(b, "%s- %s: %q\n", pad, field.Name, field.Old)
+		case "Edited":
+			fmt.Fprintf(b, "%s+/- %s: %q => %q\n", pad, field.Name, field.Old, field.New)
+		}
+	}
+}
+
+func writeObjectDiffs(b *strings.Builder, objects []*api.ObjectDiff, depth int) {
+	pad := strings.Repeat("  ", depth)
+	for _, object := range objects {
+		if object.Type == "None" {
+			continue
+		}
+		fmt.Fprintf(b, "%s%s %s {\n", pad, diffMarker(object.Type), object.Name)
+		writeFieldDiffs(b, object.Fields, depth+1)
+		writeObjectDiffs(b, object.Objects, depth+1)
+		fmt.Fprintf(b, "%s}\n", pad)
+	}
+}
+
+func diffMarker(diffType string) string {
+	switch diffType {
+	case "Added":
+		return "+"
+	case "Deleted":
+		return "-"
+	case "Edited":
+		return "+/-"
+	}
+	return ""
+}
+
+// formatUpdates describes the scheduler's planned changes, e.g. " (1 create)"
+func formatUpdates(updates map[string]uint64) string {
+	var parts []string
+	for _, kind := range sortedKeys(updates) {
+		if updates[kind] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", updates[kind], kind))
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
+
+// writePlacementFailure explains why the scheduler couldn't place a task group
+func writePlacementFailure(b *strings.Builder, group string, metric *api.AllocationMetric) {
+	fmt.Fprintf(b, "\nTask Group %q (failed to place %d allocation(s)):\n", group, metric.CoalescedFailures+1)
+	fmt.Fprintf(b, "  * Nodes evaluated: %d, filtered: %d, exhausted: %d\n", metric.NodesEvaluated, metric.NodesFiltered, metric.NodesExhausted)
+	if metric.NodesEvaluated == 0 {
+		b.WriteString("  * No nodes were eligible for evaluation (check datacenters and node pool)\n")
+	}
+	for _, class := range sortedKeys(metric.ClassFiltered) {
+		fmt.Fprintf(b, "  * Class %q: %d nodes excluded by filter\n", class, metric.ClassFiltered[class])
+	}
+	for _, constraint := range sortedKeys(metric.ConstraintFiltered) {
+		fmt.Fprintf(b, "  * Constraint %q: %d nodes excluded by filter\n", constraint, metric.ConstraintFiltered[constraint])
+	}
+	for _, dimension := range sortedKeys(metric.DimensionExhausted) {
+		fmt.Fprintf(b, "  * Resources exhausted on %d nodes: %s\n", metric.DimensionExhausted[dimension], dimension)
+	}
+	for _, quota := range metric.QuotaExhausted {
+		fmt.Fprintf(b, "  * Quota limit hit %q\n", quota)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,75 @@
+package nomad
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+func TestFormatPlan(t *testing.T) {
+	resp := &api.JobPlanResponse{
+		Diff: &api.JobDiff{
+			Type: "Edited",
+			ID:   "devpod-test",
+			TaskGroups: []*api.TaskGroupDiff{{
+				Type:    "Edited",
+				Name:    "devpod-test",
+				Updates: map[string]uint64{"create/destroy update": 1, "ignore": 0},
+				Tasks: []*api.TaskDiff{{
+					Type:        "Edited",
+					Name:        "devpod-test",
+					Annotations: []string{"forces create/destroy update"},
+					Objects: []*api.ObjectDiff{{
+						Type:   "Edited",
+						Name:   "Resources",
+						Fields: []*api.FieldDiff{{Type: "Edited", Name: "CPU", Old: "200", New: "500"}},
+					}},
+				}},
+			}},
+		},
+		Warnings: "1 warning:\n\n* Group \"devpod-test\" has warnings\n",
+	}
+
+	got := FormatPlan(resp)
+
+	for _, want := range []string{
+		`+/- Job: "devpod-test"`,
+		`+/- Task Group: "devpod-test" (1 create/destroy update)`,
+		`  +/- Task: "devpod-test" (forces create/destroy update)`,
+		`      +/- CPU: "200" => "500"`,
+		"- All tasks successfully allocated.",
+		"Warnings:",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in plan:\n%s", want, got)
+		}
+	}
+}
+
+func TestFormatPlan_PlacementFailure(t *testing.T) {
+	resp := &api.JobPlanResponse{
+		FailedTGAllocs: map[string]*api.AllocationMetric{
+			"devpod-test": {
+				NodesEvaluated:     3,
+				NodesFiltered:      2,
+				NodesExhausted:     1,
+				ConstraintFiltered: map[string]int{"${node.class} = gpu": 2},
+				DimensionExhausted: map[string]int{"memory": 1},
+			},
+		},
+	}
+
+	got := FormatPlan(resp)
+
+	for _, want := range []string{
+		"WARNING: Failed to place all allocations.",
+		`Task Group "devpod-test" (failed to place 1 allocation(s)):`,
+		`Constraint "${node.class} = gpu": 2 nodes excluded by filter`,
+		"Resources exhausted on 1 nodes: memory",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in plan:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package options
+
+import "fmt"
+
+// How the bootstrap script gets the tools the workspace needs
+const (
+	BootstrapModeInstall  = "install"  // Install them with apt-get on every start
+	BootstrapModePrebuilt = "prebuilt" // The image brings them; the task fails if any is missing
+	BootstrapModeAuto     = "auto"     // Install only the ones missing from the image
+)
+
+// Default bootstrap mode, which works with any Debian or Ubuntu image
+const defaultBootstrapMode = BootstrapModeInstall
+
+// ValidateBootstrap validates the bootstrap mode
+func (o *Options) ValidateBootstrap() error {
+	switch o.BootstrapMode {
+	case BootstrapModeInstall, BootstrapModePrebuilt, BootstrapModeAuto:
+		return nil
+	default:
+		return fmt.Errorf("invalid NOMAD_BOOTSTRAP_MODE: %s (must be %s, %s or %s)", o.BootstrapMode, BootstrapModeInstall, BootstrapModePrebuilt, BootstrapModeAuto)
+	}
+}
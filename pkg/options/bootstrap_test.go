@@ -0,0 +1,18 @@
+package options
+
+import "testing"
+
+func TestValidateBootstrap(t *testing.T) {
+	for _, mode := range []string{BootstrapModeInstall, BootstrapModePrebuilt, BootstrapModeAuto} {
+		options := Options{BootstrapMode: mode}
+		if err := options.ValidateBootstrap(); err != nil {
+			t.Errorf("ValidateBootstrap(%q) error = %v", mode, err)
+		}
+	}
+	for _, mode := range []string{"", "apk"} {
+		options := Options{BootstrapMode: mode}
+		if err := options.ValidateBootstrap(); err == nil {
+			t.Errorf("ValidateBootstrap(%q) expected error", mode)
+		}
+	}
+}
@@ -21,10 +21,66 @@ type ConfigFile struct {
 	NomadNamespace string `yaml:"nomad_namespace"`
 	NomadRegion    string `yaml:"nomad_region"`
 
+	// Nomad task driver of the workspace task
+	NomadTaskDriver string `yaml:"nomad_task_driver"`
+
+	// Workspace image and how its tools are installed
+	NomadImage         string `yaml:"nomad_image"`
+	NomadBootstrapMode string `yaml:"nomad_bootstrap_mode"`
+
+	// Networking of the workspace task group
+	NomadNetworkMode string `yaml:"nomad_network_mode"`
+
+	// Service registration of the exposed ports
+	NomadServiceProvider string   `yaml:"nomad_service_provider"`
+	NomadServiceTags     []string `yaml:"nomad_service_tags"`
+	NomadServiceRouting  string   `yaml:"nomad_service_routing"`
+	NomadServiceDomain   string   `yaml:"nomad_service_domain"`
+	NomadOwner           string   `yaml:"nomad_owner"`
+
+	// Restart, reschedule and disconnect behavior of the workspace
+	NomadRestartAttempts     *int   `yaml:"nomad_restart_attempts"`
+	NomadRestartInterval     string `yaml:"nomad_restart_interval"`
+	NomadRestartDelay        string `yaml:"nomad_restart_delay"`
+	NomadRescheduleAttempts  *int   `yaml:"nomad_reschedule_attempts"`
+	NomadRescheduleInterval  string `yaml:"nomad_reschedule_interval"`
+	NomadDisconnectLostAfter string `yaml:"nomad_disconnect_lost_after"`
+	NomadDisconnectStopAfter string `yaml:"nomad_disconnect_stop_after"`
+	NomadDisconnectReplace   *bool  `yaml:"nomad_disconnect_replace"`
+	NomadKillTimeout         string `yaml:"nomad_kill_timeout"`
+	NomadKillSignal          string `yaml:"nomad_kill_signal"`
+
+	// Host mounts of the workspace task
+	NomadMountDockerSocket *bool  `yaml:"nomad_mount_docker_socket"`
+	NomadMountDockerCerts  *bool  `yaml:"nomad_mount_docker_certs"`
+	NomadMountRegistryCA   *bool  `yaml:"nomad_mount_registry_ca"`
+	NomadRegistryCAPath    string `yaml:"nomad_registry_ca_path"`
+
+	// Image pulls
+	NomadForcePull *bool `yaml:"nomad_force_pull"`
+
+	// Job file merged into the generated job
+	NomadJobTemplate string `yaml:"nomad_job_template"`
+
+	// Job placement scope
+	NomadDatacenters []string `yaml:"nomad_datacenters"`
+	NomadNodePool    string   `yaml:"nomad_node_pool"`
+	NomadPriority    *int     `yaml:"nomad_priority"`
+
 	// GPU configuration
-	NomadGPU                  *bool  `yaml:"nomad_gpu"`
-	NomadGPUCount             *int   `yaml:"nomad_gpu_count"`
-	NomadGPUComputeCapability string `yaml:"nomad_gpu_compute_capability"`
+	NomadGPU                   *bool  `yaml:"nomad_gpu"`
+	NomadGPUCount              *int   `yaml:"nomad_gpu_count"`
+	NomadGPUComputeCapability  string `yaml:"nomad_gpu_compute_capability"`
+	NomadGPUVendor             string `yaml:"nomad_gpu_vendor"`
+	NomadGPUMinMemoryMB        *int   `yaml:"nomad_gpu_min_memory_mb"`
+	NomadGPUModel              string `yaml:"nomad_gpu_model"`
+	NomadGPUDriverVersion      string `yaml:"nomad_gpu_driver_version"`
+	NomadGPUPreferredModel     string `yaml:"nomad_gpu_preferred_model"`
+	NomadGPUPreferredMemoryMB  *int   `yaml:"nomad_gpu_preferred_memory_mb"`
+	NomadGPUArch               string `yaml:"nomad_gpu_arch"`
+	NomadGPUDedicated          string `yaml:"nomad_gpu_dedicated"`
+	NomadGPUShmSizeMB          *int   `yaml:"nomad_gpu_shm_size_mb"`
+	NomadGPUDriverCapabilities string `yaml:"nomad_gpu_driver_capabilities"`
 
 	// CSI Storage configuration
 	NomadStorageMode  string `yaml:"nomad_storage_mode"`
@@ -34,14 +90,44 @@ type ConfigFile struct {
 	NomadCSIVaultPath string `yaml:"nomad_csi_vault_path"`
 
 	// Vault configuration
-	VaultAddr       string   `yaml:"vault_addr"`
-	VaultRole       string   `yaml:"vault_role"`
-	VaultNamespace  string   `yaml:"vault_namespace"`
-	VaultChangeMode string   `yaml:"vault_change_mode"`
-	VaultPolicies   []string `yaml:"vault_policies"`
+	VaultAddr         string   `yaml:"vault_addr"`
+	VaultRole         string   `yaml:"vault_role"`
+	VaultNamespace    string   `yaml:"vault_namespace"`
+	VaultChangeMode   string   `yaml:"vault_change_mode"`
+	VaultChangeSignal string   `yaml:"vault_change_signal"`
+	VaultPolicies     []string `yaml:"vault_policies"`
+
+	// Provider-side Vault authentication
+	VaultAuthMethod string `yaml:"vault_auth_method"`
+	VaultAuthMount  string `yaml:"vault_auth_mount"`
+	VaultAuthRole   string `yaml:"vault_auth_role"`
 
 	// VaultSecrets allows defining secrets in native YAML format instead of JSON string
 	VaultSecrets []VaultSecret `yaml:"vault_secrets"`
+
+	// NomadVariables maps Nomad Variable items to environment variables
+	NomadVariables []NomadVariable `yaml:"nomad_variables"`
+
+	// Consul KV keys and service addresses mapped to environment variables
+	ConsulKV       []ConsulKV      `yaml:"consul_kv"`
+	ConsulServices []ConsulService `yaml:"consul_services"`
+
+	// Job placement
+	NomadConstraints []Constraint `yaml:"nomad_constraints"`
+	NomadAffinities  []Affinity   `yaml:"nomad_affinities"`
+	NomadSpread      []Spread     `yaml:"nomad_spread"`
+
+	// Additional tasks run next to the workspace
+	Sidecars []Sidecar `yaml:"sidecars"`
+
+	// Ports exposed on the Nomad client
+	Ports []Port `yaml:"ports"`
+
+	// Additional host paths and Nomad host volumes mounted into the workspace
+	ExtraVolumes []ExtraVolume `yaml:"extra_volumes"`
+
+	// Credentials for private image registries
+	RegistryAuth []RegistryAuth `yaml:"registry_auth"`
 }
 
 // LoadConfigFile reads and parses the .devpod/nomad.yaml file from the workspace path.
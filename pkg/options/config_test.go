@@ -57,6 +57,73 @@ vault_secrets:
     fields:
       api_key: "API_KEY"
       secret: "SECRET_VALUE"
+nomad_variables:
+  - path: "nomad/jobs/devpod/db"
+    fields:
+      password: "DB_PASSWORD"
+consul_kv:
+  - key: "dev/postgres/database"
+    env: "DB_NAME"
+consul_services:
+  - name: "postgres"
+    address_env: "DB_HOST"
+    port_env: "DB_PORT"
+nomad_datacenters:
+  - "dc1"
+  - "dc2"
+nomad_node_pool: "workspaces"
+nomad_job_template: "nomad/workspace.nomad.hcl"
+nomad_task_driver: "podman"
+nomad_mount_docker_socket: false
+nomad_registry_ca_path: "/etc/pki/registry-ca.crt"
+nomad_network_mode: "bridge"
+ports:
+  - label: "http"
+    to: 3000
+nomad_service_provider: "consul"
+nomad_service_tags:
+  - "owner={owner}"
+nomad_service_routing: "traefik"
+nomad_service_domain: "dev.example.com"
+nomad_owner: "alex"
+nomad_restart_attempts: 0
+nomad_reschedule_attempts: 3
+nomad_disconnect_lost_after: "30m"
+nomad_disconnect_replace: true
+nomad_kill_timeout: "20s"
+nomad_kill_signal: "SIGINT"
+nomad_force_pull: true
+nomad_image: "registry.example.com/devpod/base:1"
+nomad_bootstrap_mode: "prebuilt"
+registry_auth:
+  - registry: "ghcr.io"
+    vault_path: "secret/data/registry/ghcr"
+    auth_soft_fail: true
+extra_volumes:
+  - type: "host"
+    source: "datasets"
+    destination: "/datasets"
+    read_only: true
+nomad_constraints:
+  - attribute: "${node.class}"
+    value: "workspace"
+nomad_affinities:
+  - attribute: "${meta.rack}"
+    operator: "regexp"
+    value: "r1.*"
+    weight: 50
+nomad_spread:
+  - attribute: "${meta.zone}"
+    targets:
+      - value: "a"
+        percent: 70
+sidecars:
+  - name: "postgres"
+    image: "postgres:16"
+    env:
+      POSTGRES_PASSWORD: "devpod"
+    memory_mb: 512
+    lifecycle: "prestart"
 `
 	configPath := filepath.Join(devpodDir, "nomad.yaml")
 	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
@@ -106,6 +173,84 @@ vault_secrets:
 	if config.VaultSecrets[0].Fields["api_key"] != "API_KEY" {
 		t.Errorf("Expected api_key->API_KEY, got %s", config.VaultSecrets[0].Fields["api_key"])
 	}
+	if len(config.NomadVariables) != 1 {
+		t.Fatalf("Expected 1 NomadVariable, got %d", len(config.NomadVariables))
+	}
+	if config.NomadVariables[0].Path != "nomad/jobs/devpod/db" || config.NomadVariables[0].Fields["password"] != "DB_PASSWORD" {
+		t.Errorf("Unexpected NomadVariable: %+v", config.NomadVariables[0])
+	}
+	if len(config.ConsulKV) != 1 || config.ConsulKV[0].Key != "dev/postgres/database" || config.ConsulKV[0].Env != "DB_NAME" {
+		t.Errorf("Unexpected ConsulKV: %+v", config.ConsulKV)
+	}
+	if len(config.ConsulServices) != 1 || config.ConsulServices[0].AddressEnv != "DB_HOST" || config.ConsulServices[0].PortEnv != "DB_PORT" {
+		t.Errorf("Unexpected ConsulServices: %+v", config.ConsulServices)
+	}
+	if len(config.NomadDatacenters) != 2 || config.NomadNodePool != "workspaces" {
+		t.Errorf("Unexpected datacenters %v / node pool %q", config.NomadDatacenters, config.NomadNodePool)
+	}
+	if config.NomadTaskDriver != "podman" {
+		t.Errorf("Expected NomadTaskDriver=podman, got %s", config.NomadTaskDriver)
+	}
+	if config.NomadMountDockerSocket == nil || *config.NomadMountDockerSocket || config.NomadMountDockerCerts != nil {
+		t.Errorf("Expected nomad_mount_docker_socket=false and unset nomad_mount_docker_certs")
+	}
+	if config.NomadRegistryCAPath != "/etc/pki/registry-ca.crt" {
+		t.Errorf("Expected NomadRegistryCAPath=/etc/pki/registry-ca.crt, got %s", config.NomadRegistryCAPath)
+	}
+	if len(config.ExtraVolumes) != 1 || config.ExtraVolumes[0].Type != "host" || !config.ExtraVolumes[0].ReadOnly {
+		t.Errorf("Unexpected ExtraVolumes: %+v", config.ExtraVolumes)
+	}
+	if config.NomadJobTemplate != "nomad/workspace.nomad.hcl" {
+		t.Errorf("Expected NomadJobTemplate=nomad/workspace.nomad.hcl, got %s", config.NomadJobTemplate)
+	}
+	if len(config.NomadConstraints) != 1 || config.NomadConstraints[0].Attribute != "${node.class}" {
+		t.Errorf("Unexpected NomadConstraints: %+v", config.NomadConstraints)
+	}
+	if len(config.NomadAffinities) != 1 || config.NomadAffinities[0].Weight != 50 {
+		t.Errorf("Unexpected NomadAffinities: %+v", config.NomadAffinities)
+	}
+	if len(config.NomadSpread) != 1 || len(config.NomadSpread[0].Targets) != 1 || config.NomadSpread[0].Targets[0].Percent != 70 {
+		t.Errorf("Unexpected NomadSpread: %+v", config.NomadSpread)
+	}
+	if len(config.Sidecars) != 1 || config.Sidecars[0].MemoryMB != 512 || config.Sidecars[0].Env["POSTGRES_PASSWORD"] != "devpod" {
+		t.Errorf("Unexpected Sidecars: %+v", config.Sidecars)
+	}
+	if config.NomadNetworkMode != "bridge" {
+		t.Errorf("Expected NomadNetworkMode=bridge, got %s", config.NomadNetworkMode)
+	}
+	if len(config.Ports) != 1 || config.Ports[0].Label != "http" || config.Ports[0].To != 3000 {
+		t.Errorf("Unexpected Ports: %+v", config.Ports)
+	}
+	if config.NomadServiceProvider != "consul" || config.NomadServiceRouting != "traefik" || config.NomadServiceDomain != "dev.example.com" {
+		t.Errorf("Unexpected service settings: %s %s %s", config.NomadServiceProvider, config.NomadServiceRouting, config.NomadServiceDomain)
+	}
+	if len(config.NomadServiceTags) != 1 || config.NomadServiceTags[0] != "owner={owner}" {
+		t.Errorf("Unexpected NomadServiceTags: %v", config.NomadServiceTags)
+	}
+	if config.NomadOwner != "alex" {
+		t.Errorf("Expected NomadOwner=alex, got %s", config.NomadOwner)
+	}
+	if config.NomadRestartAttempts == nil || *config.NomadRestartAttempts != 0 {
+		t.Errorf("Expected NomadRestartAttempts=0, got %v", config.NomadRestartAttempts)
+	}
+	if config.NomadRescheduleAttempts == nil || *config.NomadRescheduleAttempts != 3 {
+		t.Errorf("Expected NomadRescheduleAttempts=3, got %v", config.NomadRescheduleAttempts)
+	}
+	if config.NomadDisconnectLostAfter != "30m" || config.NomadDisconnectReplace == nil || !*config.NomadDisconnectReplace {
+		t.Errorf("Unexpected disconnect settings: %s %v", config.NomadDisconnectLostAfter, config.NomadDisconnectReplace)
+	}
+	if config.NomadKillTimeout != "20s" || config.NomadKillSignal != "SIGINT" {
+		t.Errorf("Unexpected kill settings: %s %s", config.NomadKillTimeout, config.NomadKillSignal)
+	}
+	if config.NomadForcePull == nil || !*config.NomadForcePull {
+		t.Errorf("Expected NomadForcePull=true, got %v", config.NomadForcePull)
+	}
+	if config.NomadImage != "registry.example.com/devpod/base:1" || config.NomadBootstrapMode != "prebuilt" {
+		t.Errorf("Unexpected bootstrap settings: %s %s", config.NomadImage, config.NomadBootstrapMode)
+	}
+	if len(config.RegistryAuth) != 1 || config.RegistryAuth[0].VaultPath != "secret/data/registry/ghcr" || !config.RegistryAuth[0].SoftFail {
+		t.Errorf("Unexpected registry auth: %v", config.RegistryAuth)
+	}
 }
 
 func TestLoadConfigFile_InvalidYAML(t *testing.T) {
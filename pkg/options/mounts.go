@@ -0,0 +1,79 @@
+package options
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// Extra volume types
+const (
+	ExtraVolumeBind = "bind" // Host path bind-mounted through the task driver
+	ExtraVolumeHost = "host" // Nomad host volume registered on the client
+)
+
+// Default host path of the registry CA certificate mounted into workspaces
+const defaultRegistryCAPath = "/usr/local/share/ca-certificates/registry.cluster.crt"
+
+// ExtraVolume mounts a host path or a Nomad host volume into the workspace
+type ExtraVolume struct {
+	Type        string `json:"type,omitempty"`                       // bind (default) or host
+	Source      string `json:"source"`                               // Host path for bind, host volume name for host
+	Destination string `json:"destination"`                          // Path inside the workspace
+	ReadOnly    bool   `json:"read_only,omitempty" yaml:"read_only"` // Mount read-only
+}
+
+// getExtraVolumes returns extra volumes from env var (JSON) or config file.
+// Environment variable takes precedence.
+func getExtraVolumes(configFile *ConfigFile) ([]ExtraVolume, error) {
+	volumesJSON := os.Getenv("NOMAD_EXTRA_VOLUMES_JSON")
+	if volumesJSON != "" {
+		var volumes []ExtraVolume
+		if err := json.Unmarshal([]byte(volumesJSON), &volumes); err != nil {
+			return nil, fmt.Errorf("unmarshal NOMAD_EXTRA_VOLUMES_JSON: %w", err)
+		}
+		return volumes, nil
+	}
+
+	if configFile != nil && len(configFile.ExtraVolumes) > 0 {
+		return configFile.ExtraVolumes, nil
+	}
+
+	return nil, nil
+}
+
+// ValidateMounts validates the registry CA path and the extra volumes
+func (o *Options) ValidateMounts() error {
+	if o.MountRegistryCA {
+		// update-ca-certificates only picks up files ending in .crt
+		if !path.IsAbs(o.RegistryCAPath) || !strings.HasSuffix(o.RegistryCAPath, ".crt") {
+			return fmt.Errorf("invalid NOMAD_REGISTRY_CA_PATH: %s (must be an absolute path ending in .crt)", o.RegistryCAPath)
+		}
+	}
+
+	for i, v := range o.ExtraVolumes {
+		if v.Type != "" && v.Type != ExtraVolumeBind && v.Type != ExtraVolumeHost {
+			return fmt.Errorf("extra volume at index %d has invalid type: %s (must be %s or %s)", i, v.Type, ExtraVolumeBind, ExtraVolumeHost)
+		}
+		if v.Source == "" {
+			return fmt.Errorf("extra volume at index %d has empty source", i)
+		}
+		if !path.IsAbs(v.Destination) {
+			return fmt.Errorf("extra volume at index %d (%s) has invalid destination: %q (must be an absolute path)", i, v.Source, v.Destination)
+		}
+		if v.Type == ExtraVolumeHost {
+			continue
+		}
+		// Bind mounts are rendered as Docker's source:destination[:ro] syntax
+		if !path.IsAbs(v.Source) {
+			return fmt.Errorf("extra volume at index %d has invalid source: %s (bind mounts need an absolute host path)", i, v.Source)
+		}
+		if strings.Contains(v.Source, ":") || strings.Contains(v.Destination, ":") {
+			return fmt.Errorf("extra volume at index %d (%s) must not contain ':' in its paths", i, v.Source)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package options
+
+import (
+	"testing"
+)
+
+func TestValidateMounts(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		wantErr bool
+	}{
+		{
+			name:    "defaults",
+			options: Options{MountRegistryCA: true, RegistryCAPath: defaultRegistryCAPath},
+		},
+		{
+			name:    "registry CA disabled ignores path",
+			options: Options{MountRegistryCA: false, RegistryCAPath: ""},
+		},
+		{
+			name:    "registry CA relative path",
+			options: Options{MountRegistryCA: true, RegistryCAPath: "certs/ca.crt"},
+			wantErr: true,
+		},
+		{
+			name:    "registry CA without .crt",
+			options: Options{MountRegistryCA: true, RegistryCAPath: "/etc/pki/ca.pem"},
+			wantErr: true,
+		},
+		{
+			name: "valid extra volumes",
+			options: Options{ExtraVolumes: []ExtraVolume{
+				{Source: "/opt/tools", Destination: "/opt/tools", ReadOnly: true},
+				{Type: ExtraVolumeHost, Source: "datasets", Destination: "/datasets"},
+			}},
+		},
+		{
+			name:    "invalid type",
+			options: Options{ExtraVolumes: []ExtraVolume{{Type: "csi", Source: "data", Destination: "/data"}}},
+			wantErr: true,
+		},
+		{
+			name:    "empty source",
+			options: Options{ExtraVolumes: []ExtraVolume{{Destination: "/data"}}},
+			wantErr: true,
+		},
+		{
+			name:    "relative destination",
+			options: Options{ExtraVolumes: []ExtraVolume{{Source: "/data", Destination: "data"}}},
+			wantErr: true,
+		},
+		{
+			name:    "bind with relative source",
+			options: Options{ExtraVolumes: []ExtraVolume{{Source: "data", Destination: "/data"}}},
+			wantErr: true,
+		},
+		{
+			name:    "bind with colon",
+			options: Options{ExtraVolumes: []ExtraVolume{{Source: "/data:rw", Destination: "/data"}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.options.ValidateMounts()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateMounts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetExtraVolumes_EnvTakesPrecedence(t *testing.T) {
+	t.Setenv("NOMAD_EXTRA_VOLUMES_JSON", `[{"type":"host","source":"datasets","destination":"/datasets","read_only":true}]`)
+	configFile := &ConfigFile{
+		ExtraVolumes: []ExtraVolume{{Source: "/opt/tools", Destination: "/opt/tools"}},
+	}
+
+	volumes, err := getExtraVolumes(configFile)
+	if err != nil {
+		t.Fatalf("getExtraVolumes failed: %v", err)
+	}
+	if len(volumes) != 1 || volumes[0].Source != "datasets" || !volumes[0].ReadOnly {
+		t.Errorf("Expected extra volume from env, got %v", volumes)
+	}
+}
+
+func TestGetExtraVolumes_InvalidJSON(t *testing.T) {
+	t.Setenv("NOMAD_EXTRA_VOLUMES_JSON", `[{"source":`)
+
+	if _, err := getExtraVolumes(nil); err == nil {
+		t.Error("Expected error for invalid NOMAD_EXTRA_VOLUMES_JSON")
+	}
+}
@@ -0,0 +1,84 @@
+package options
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Network modes of the workspace task group
+const (
+	NetworkModeBridge    = "bridge" // Nomad bridge network shared by the group's tasks
+	NetworkModeHost      = "host"   // Client's network namespace
+	NetworkModeCNIPrefix = "cni/"   // CNI network configured on the client, e.g. "cni/mynet"
+)
+
+// Nomad only accepts port labels made of these characters
+var portLabelPattern = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+// Port is a port of the workspace exposed on the Nomad client
+type Port struct {
+	Label  string `json:"label"`            // Port label, also used in NOMAD_PORT_<label>
+	To     int    `json:"to,omitempty"`     // Port inside the workspace, defaults to the host port
+	Static int    `json:"static,omitempty"` // Fixed host port, dynamic when 0
+}
+
+// getPorts returns ports from env var (JSON) or config file.
+// Environment variable takes precedence.
+func getPorts(configFile *ConfigFile) ([]Port, error) {
+	portsJSON := os.Getenv("NOMAD_PORTS_JSON")
+	if portsJSON != "" {
+		var ports []Port
+		if err := json.Unmarshal([]byte(portsJSON), &ports); err != nil {
+			return nil, fmt.Errorf("unmarshal NOMAD_PORTS_JSON: %w", err)
+		}
+		return ports, nil
+	}
+
+	if configFile != nil && len(configFile.Ports) > 0 {
+		return configFile.Ports, nil
+	}
+
+	return nil, nil
+}
+
+// ValidateNetwork validates the network mode and the ports
+func (o *Options) ValidateNetwork() error {
+	switch {
+	case o.NetworkMode == "", o.NetworkMode == NetworkModeBridge:
+	case o.NetworkMode == NetworkModeHost:
+		if len(o.Sidecars) > 0 {
+			return fmt.Errorf("NOMAD_NETWORK_MODE %s does not support sidecars (use %s or a CNI network)", NetworkModeHost, NetworkModeBridge)
+		}
+		// Sysbox containers can't share the client's network namespace
+		if o.TaskDriver == TaskDriverSysbox {
+			return fmt.Errorf("NOMAD_NETWORK_MODE %s is not supported by the %s task driver", NetworkModeHost, TaskDriverSysbox)
+		}
+	case strings.HasPrefix(o.NetworkMode, NetworkModeCNIPrefix) && len(o.NetworkMode) > len(NetworkModeCNIPrefix):
+	default:
+		return fmt.Errorf("invalid NOMAD_NETWORK_MODE: %s (must be %s, %s or %s<name>)", o.NetworkMode, NetworkModeBridge, NetworkModeHost, NetworkModeCNIPrefix)
+	}
+
+	labels := map[string]bool{}
+	for i, p := range o.Ports {
+		if !portLabelPattern.MatchString(p.Label) {
+			return fmt.Errorf("port at index %d has invalid label: %q (must only contain letters, digits and underscores)", i, p.Label)
+		}
+		if labels[p.Label] {
+			return fmt.Errorf("port at index %d has duplicate label: %s", i, p.Label)
+		}
+		labels[p.Label] = true
+
+		if p.To < 0 || p.To > 65535 || p.Static < 0 || p.Static > 65535 {
+			return fmt.Errorf("port at index %d (%s) is out of range (must be between 1 and 65535)", i, p.Label)
+		}
+		// Without a network namespace of its own the workspace listens on the host port
+		if o.NetworkMode == NetworkModeHost && p.To != 0 {
+			return fmt.Errorf("port at index %d (%s) can't set to in %s network mode", i, p.Label, NetworkModeHost)
+		}
+	}
+
+	return nil
+}
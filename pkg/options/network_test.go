@@ -0,0 +1,105 @@
+package options
+
+import (
+	"testing"
+)
+
+func TestValidateNetwork(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		wantErr bool
+	}{
+		{
+			name: "driver default with ports",
+			options: Options{Ports: []Port{
+				{Label: "http", To: 3000},
+				{Label: "debug_port", Static: 9229},
+			}},
+		},
+		{
+			name:    "bridge",
+			options: Options{NetworkMode: NetworkModeBridge},
+		},
+		{
+			name:    "host with static port",
+			options: Options{NetworkMode: NetworkModeHost, Ports: []Port{{Label: "http", Static: 8080}}},
+		},
+		{
+			name:    "cni network",
+			options: Options{NetworkMode: "cni/devnet"},
+		},
+		{
+			name:    "cni without name",
+			options: Options{NetworkMode: "cni/"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown mode",
+			options: Options{NetworkMode: "none"},
+			wantErr: true,
+		},
+		{
+			name:    "host with sidecars",
+			options: Options{NetworkMode: NetworkModeHost, Sidecars: []Sidecar{{Name: "redis", Image: "redis:7"}}},
+			wantErr: true,
+		},
+		{
+			name:    "host with sysbox",
+			options: Options{NetworkMode: NetworkModeHost, TaskDriver: TaskDriverSysbox},
+			wantErr: true,
+		},
+		{
+			name:    "host with mapped port",
+			options: Options{NetworkMode: NetworkModeHost, Ports: []Port{{Label: "http", To: 3000}}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid label",
+			options: Options{Ports: []Port{{Label: "dev-server"}}},
+			wantErr: true,
+		},
+		{
+			name:    "duplicate label",
+			options: Options{Ports: []Port{{Label: "http"}, {Label: "http", To: 8080}}},
+			wantErr: true,
+		},
+		{
+			name:    "port out of range",
+			options: Options{Ports: []Port{{Label: "http", Static: 70000}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.options.ValidateNetwork()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateNetwork() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetPorts_EnvTakesPrecedence(t *testing.T) {
+	t.Setenv("NOMAD_PORTS_JSON", `[{"label":"http","to":3000}]`)
+	configFile := &ConfigFile{
+		Ports: []Port{{Label: "debug", Static: 9229}},
+	}
+
+	ports, err := getPorts(configFile)
+	if err != nil {
+		t.Fatalf("getPorts failed: %v", err)
+	}
+	if len(ports) != 1 || ports[0].Label != "http" || ports[0].To != 3000 {
+		t.Errorf("Expected port from env, got %v", ports)
+	}
+}
+
+func TestGetPorts_InvalidJSON(t *testing.T) {
+	t.Setenv("NOMAD_PORTS_JSON", `[{"label":`)
+
+	if _, err := getPorts(nil); err == nil {
+		t.Error("Expected error for invalid NOMAD_PORTS_JSON")
+	}
+}
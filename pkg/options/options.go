@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/loft-sh/devpod/pkg/driver"
 	"github.com/loft-sh/log"
@@ -16,6 +19,33 @@ import (
 type VaultSecret struct {
 	Path   string            `json:"path"`   // Vault KV v2 path (e.g., "secret/data/aws/creds")
 	Fields map[string]string `json:"fields"` // vault_field -> ENV_VAR_NAME mapping
+
+	// Optional per-secret template settings, falling back to the global Vault settings
+	ChangeMode   string `json:"change_mode,omitempty" yaml:"change_mode"`     // restart, noop or signal
+	ChangeSignal string `json:"change_signal,omitempty" yaml:"change_signal"` // Signal sent in signal mode (e.g., "SIGUSR1")
+	Splay        string `json:"splay,omitempty"`                              // Random delay before the change action (e.g., "30s")
+}
+
+// NomadVariable represents a Nomad Variable path and its item mappings
+type NomadVariable struct {
+	Path   string            `json:"path"`   // Nomad Variable path (e.g., "nomad/jobs/devpod/db")
+	Fields map[string]string `json:"fields"` // variable_item -> ENV_VAR_NAME mapping
+}
+
+// ConsulKV maps a Consul KV key to an environment variable
+type ConsulKV struct {
+	Key     string  `json:"key"`     // Consul KV key (e.g., "dev/postgres/database")
+	Env     string  `json:"env"`     // Environment variable name
+	Default *string `json:"default"` // Optional value used when the key does not exist
+}
+
+// ConsulService maps the address of a Consul service to environment variables
+type ConsulService struct {
+	Name       string `json:"name"`                           // Service name (e.g., "postgres")
+	Tag        string `json:"tag,omitempty"`                  // Optional tag filter
+	Datacenter string `json:"datacenter,omitempty"`           // Optional datacenter, defaults to the local one
+	AddressEnv string `json:"address_env" yaml:"address_env"` // Environment variable for the service address
+	PortEnv    string `json:"port_env" yaml:"port_env"`       // Environment variable for the service port
 }
 
 type Options struct {
@@ -29,17 +59,91 @@ type Options struct {
 	Region    string
 	TaskName  string
 
+	// DevPod agent data directory (AGENT_DATA_PATH), inside WorkspacePath() by default
+	AgentDataPath string
+
+	// Job placement scope
+	Datacenters []string // Datacenters the job may run in, empty uses Nomad's default
+	NodePool    string   // Node pool the job runs in, empty uses Nomad's default
+	Priority    int      // Job priority (1-100), higher priorities can preempt lower ones
+
 	Token string
 
 	DriverOpts *driver.RunOptions
 
+	// Nomad task driver of the workspace task: docker, podman or sysbox
+	TaskDriver string
+
+	// Workspace image when DevPod doesn't pass one, and how the bootstrap script
+	// gets the tools the workspace needs: install, prebuilt or auto
+	Image         string
+	BootstrapMode string
+
+	// Networking of the workspace task group
+	NetworkMode string // "" (Docker's bridge network), bridge, host or cni/<name>
+	Ports       []Port // Ports exposed on the Nomad client
+
+	// Service registration of the exposed ports
+	ServiceProvider string   // "" (no services), nomad or consul
+	ServiceTags     []string // Tags of every service, with {job}, {owner} and {label} placeholders
+	ServiceRouting  string   // "" or the ingress router to generate tags for: traefik or fabio
+	ServiceDomain   string   // Domain of the preview hostnames, e.g. "dev.example.com"
+	Owner           string   // Owner of the workspace, defaults to the local user
+
+	// Restart, reschedule and disconnect behavior of the workspace
+	RestartAttempts     int    // Restarts within RestartInterval before the allocation fails
+	RestartInterval     string // e.g. "30m"
+	RestartDelay        string // Delay before each restart
+	RescheduleAttempts  int    // Reschedules onto another node within RescheduleInterval, 0 never reschedules
+	RescheduleInterval  string // e.g. "1h"
+	DisconnectLostAfter string // How long a disconnected client's workspace is kept before it's lost
+	DisconnectStopAfter string // Stop the workspace once its client has been disconnected this long
+	DisconnectReplace   bool   // Replace the workspace on another node while its client is disconnected
+	KillTimeout         string // Time between the kill signal and SIGKILL, for the final sync
+	KillSignal          string // Signal that stops the workspace: SIGTERM or SIGINT
+
+	// Host mounts of the workspace task
+	MountDockerSocket bool          // Client's Docker socket, docker driver only
+	MountDockerCerts  bool          // Client's /etc/docker/certs.d
+	MountRegistryCA   bool          // Registry CA certificate at RegistryCAPath
+	RegistryCAPath    string        // Host path of the registry CA certificate
+	ExtraVolumes      []ExtraVolume // Additional host paths and Nomad host volumes
+
+	// Image pulls of the workspace and sidecar tasks
+	RegistryAuth []RegistryAuth // Credentials for private registries
+	ForcePull    bool           // Pull images even if they are cached on the client
+
 	// Vault configuration
-	VaultAddr       string
-	VaultRole       string
-	VaultNamespace  string
-	VaultChangeMode string
-	VaultPolicies   []string
-	VaultSecrets    []VaultSecret
+	VaultAddr         string
+	VaultRole         string
+	VaultNamespace    string
+	VaultChangeMode   string
+	VaultChangeSignal string
+	VaultPolicies     []string
+	VaultSecrets      []VaultSecret
+
+	// Nomad Variables rendered into the workspace like Vault secrets
+	NomadVariables []NomadVariable
+
+	// Consul KV keys and service addresses rendered into the workspace
+	ConsulKV       []ConsulKV
+	ConsulServices []ConsulService
+
+	// User-defined job placement
+	Constraints []Constraint
+	Affinities  []Affinity
+	Spreads     []Spread
+
+	// Additional tasks sharing the workspace's network namespace
+	Sidecars []Sidecar
+
+	// Path to an HCL or JSON job file merged into the generated job
+	JobTemplate string
+
+	// Provider-side Vault authentication (used to fetch CSI credentials)
+	VaultAuthMethod string // "token" (default), "token_file", "approle", "jwt" or "nomad"
+	VaultAuthMount  string // Auth mount path, defaults depend on the method
+	VaultAuthRole   string // Role for jwt and nomad logins
 
 	// CSI Storage configuration
 	StorageMode  string // "ephemeral" (default) or "persistent"
@@ -52,14 +156,32 @@ type Options struct {
 	GPUEnabled           bool
 	GPUCount             int
 	GPUComputeCapability string
+	GPUVendor            string // nvidia, amd, intel or a raw device name (e.g., "nvidia/gpu/A100-MIG-1g.10gb")
+
+	// GPU device constraints and affinities (evaluated against the device plugin's attributes)
+	GPUMinMemoryMB       int    // Minimum memory per GPU in MiB, 0 accepts any
+	GPUModel             string // Regular expression the GPU model must match
+	GPUDriverVersion     string // Version constraint on the GPU driver (e.g., ">= 535.0")
+	GPUPreferredModel    string // Regular expression for preferred GPU models
+	GPUPreferredMemoryMB int    // Preferred minimum memory per GPU in MiB
+
+	// GPU node placement and container settings
+	GPUArch               string // Required CPU architecture of GPU nodes, "any" disables the constraint
+	GPUDedicated          string // Policy for nodes with meta.gpu-dedicated=true: any, exclude or require
+	GPUShmSizeMB          int    // Shared memory size of the container in MB
+	GPUDriverCapabilities string // NVIDIA_DRIVER_CAPABILITIES for NVIDIA GPUs
 }
 
 const (
-	defaultCpu             = "200"
-	defaultMemoryMB        = "512"
-	defaultDiskMB          = "300"
-	defaultVaultRole       = "nomad-workloads"
-	defaultVaultChangeMode = "restart"
+	defaultCpu               = "200"
+	defaultMemoryMB          = "512"
+	defaultDiskMB            = "300"
+	defaultVaultRole         = "nomad-workloads"
+	defaultVaultChangeMode   = "restart"
+	defaultVaultChangeSignal = "SIGHUP"
+	defaultVaultAuthMethod   = "token"
+	defaultPriority          = 50
+	defaultTaskDriver        = TaskDriverDocker
 
 	// CSI Storage defaults
 	defaultStorageMode = "ephemeral"
@@ -67,11 +189,36 @@ const (
 	defaultCSIPool     = "nomad"
 
 	// GPU defaults
-	defaultGPUCount = 1
+	defaultGPUCount  = 1
+	defaultGPUVendor = GPUVendorNVIDIA
+
+	defaultGPUArch               = "amd64"
+	defaultGPUDedicated          = GPUDedicatedExclude
+	defaultGPUShmSizeMB          = 2048
+	defaultGPUDriverCapabilities = "compute,utility"
+
+	// GPU vendor constants
+	GPUVendorNVIDIA = "nvidia"
+	GPUVendorAMD    = "amd"
+	GPUVendorIntel  = "intel"
+
+	// GPU placement constants
+	GPUArchAny          = "any"
+	GPUDedicatedAny     = "any"
+	GPUDedicatedExclude = "exclude"
+	GPUDedicatedRequire = "require"
+
+	// Parent directory of the per-workspace directories on the Nomad clients
+	workspaceHostRoot = "/tmp/devpod-workspaces"
 
 	// Storage mode constants
 	StorageModeEphemeral  = "ephemeral"
 	StorageModePersistent = "persistent"
+
+	// Task driver constants
+	TaskDriverDocker = "docker" // Privileged Docker using the client's Docker daemon
+	TaskDriverPodman = "podman" // Rootless Podman with a nested Docker daemon
+	TaskDriverSysbox = "sysbox" // Docker with the Sysbox runtime and a nested Docker daemon
 )
 
 // Read ENV Vars for option overrides
@@ -117,17 +264,95 @@ func DefaultOptions() (*Options, error) {
 		return nil, err
 	}
 
+	// Parse Nomad Variables from env or config
+	nomadVariables, err := getNomadVariables(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse Consul templates from env or config
+	consulKV, err := getConsulKV(configFile)
+	if err != nil {
+		return nil, err
+	}
+	consulServices, err := getConsulServices(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse job placement from env or config
+	constraints, err := getConstraints(configFile)
+	if err != nil {
+		return nil, err
+	}
+	affinities, err := getAffinities(configFile)
+	if err != nil {
+		return nil, err
+	}
+	spreads, err := getSpreads(configFile)
+	if err != nil {
+		return nil, err
+	}
+	priority, err := getPriority(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse sidecar tasks from env or config
+	sidecars, err := getSidecars(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	ports, err := getPorts(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceTags, err := getServiceTags(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse extra volumes from env or config
+	extraVolumes, err := getExtraVolumes(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	registryAuth, err := getRegistryAuth(configFile)
+	if err != nil {
+		return nil, err
+	}
+
 	// Parse GPU configuration using config file as fallback
 	var gpuConfigValue *bool
 	var gpuCountConfigValue *int
 	var gpuCapabilityConfig string
+	var gpuMinMemoryConfig, gpuPreferredMemoryConfig, gpuShmSizeConfig *int
+	var mountSocketConfig, mountCertsConfig, mountCAConfig, forcePullConfig *bool
+	var restartAttemptsConfig, rescheduleAttemptsConfig *int
+	var disconnectReplaceConfig *bool
 	if configFile != nil {
+		restartAttemptsConfig = configFile.NomadRestartAttempts
+		rescheduleAttemptsConfig = configFile.NomadRescheduleAttempts
+		disconnectReplaceConfig = configFile.NomadDisconnectReplace
+		mountSocketConfig = configFile.NomadMountDockerSocket
+		mountCertsConfig = configFile.NomadMountDockerCerts
+		mountCAConfig = configFile.NomadMountRegistryCA
+		forcePullConfig = configFile.NomadForcePull
 		gpuConfigValue = configFile.NomadGPU
 		gpuCountConfigValue = configFile.NomadGPUCount
 		gpuCapabilityConfig = configFile.NomadGPUComputeCapability
+		gpuMinMemoryConfig = configFile.NomadGPUMinMemoryMB
+		gpuPreferredMemoryConfig = configFile.NomadGPUPreferredMemoryMB
+		gpuShmSizeConfig = configFile.NomadGPUShmSizeMB
 	}
 	gpuEnabled := getEnvOrConfigBool("NOMAD_GPU", gpuConfigValue, false)
 	gpuCount := getEnvOrConfigInt("NOMAD_GPU_COUNT", gpuCountConfigValue, defaultGPUCount)
+	gpuMinMemoryMB := getEnvOrConfigInt("NOMAD_GPU_MIN_MEMORY_MB", gpuMinMemoryConfig, 0)
+	gpuPreferredMemoryMB := getEnvOrConfigInt("NOMAD_GPU_PREFERRED_MEMORY_MB", gpuPreferredMemoryConfig, 0)
+	gpuShmSizeMB := getEnvOrConfigInt("NOMAD_GPU_SHM_SIZE_MB", gpuShmSizeConfig, defaultGPUShmSizeMB)
 
 	// Get config values for other fields
 	var cfg ConfigFile
@@ -136,23 +361,79 @@ func DefaultOptions() (*Options, error) {
 	}
 
 	opts := &Options{
-		DiskMB:     getEnvOrConfig("NOMAD_DISKMB", cfg.NomadDiskMB, defaultDiskMB),
-		Token:      "",
-		Namespace:  getEnvOrConfig("NOMAD_NAMESPACE", cfg.NomadNamespace, ""),
-		Region:     getEnvOrConfig("NOMAD_REGION", cfg.NomadRegion, ""),
-		TaskName:   getEnv("MACHINE_ID", "devpod"),
-		CPU:        getEnvOrConfig("NOMAD_CPU", cfg.NomadCPU, defaultCpu),
-		MemoryMB:   getEnvOrConfig("NOMAD_MEMORYMB", cfg.NomadMemoryMB, defaultMemoryMB),
-		JobId:      getEnv("MACHINE_ID", "devpod"), // set by devpod for machine providers
-		DriverOpts: runOptions,
+		DiskMB:    getEnvOrConfig("NOMAD_DISKMB", cfg.NomadDiskMB, defaultDiskMB),
+		Token:     "",
+		Namespace: getEnvOrConfig("NOMAD_NAMESPACE", cfg.NomadNamespace, ""),
+		Region:    getEnvOrConfig("NOMAD_REGION", cfg.NomadRegion, ""),
+
+		Datacenters:   getDatacenters(configFile),
+		NodePool:      getEnvOrConfig("NOMAD_NODE_POOL", cfg.NomadNodePool, ""),
+		Priority:      priority,
+		TaskName:      getEnv("MACHINE_ID", "devpod"),
+		CPU:           getEnvOrConfig("NOMAD_CPU", cfg.NomadCPU, defaultCpu),
+		MemoryMB:      getEnvOrConfig("NOMAD_MEMORYMB", cfg.NomadMemoryMB, defaultMemoryMB),
+		JobId:         getEnv("MACHINE_ID", "devpod"), // set by devpod for machine providers
+		AgentDataPath: getEnv("AGENT_DATA_PATH", ""),
+		DriverOpts:    runOptions,
+		TaskDriver:    getEnvOrConfig("NOMAD_TASK_DRIVER", cfg.NomadTaskDriver, defaultTaskDriver),
+		Image:         getEnvOrConfig("NOMAD_IMAGE", cfg.NomadImage, ""),
+		BootstrapMode: getEnvOrConfig("NOMAD_BOOTSTRAP_MODE", cfg.NomadBootstrapMode, defaultBootstrapMode),
+
+		NetworkMode: getEnvOrConfig("NOMAD_NETWORK_MODE", cfg.NomadNetworkMode, ""),
+		Ports:       ports,
+
+		ServiceProvider: getEnvOrConfig("NOMAD_SERVICE_PROVIDER", cfg.NomadServiceProvider, ""),
+		ServiceTags:     serviceTags,
+		ServiceRouting:  getEnvOrConfig("NOMAD_SERVICE_ROUTING", cfg.NomadServiceRouting, ""),
+		ServiceDomain:   getEnvOrConfig("NOMAD_SERVICE_DOMAIN", cfg.NomadServiceDomain, ""),
+		Owner:           getEnvOrConfig("NOMAD_OWNER", cfg.NomadOwner, defaultOwner()),
+
+		RestartAttempts:     getEnvOrConfigInt("NOMAD_RESTART_ATTEMPTS", restartAttemptsConfig, defaultRestartAttempts),
+		RestartInterval:     getEnvOrConfig("NOMAD_RESTART_INTERVAL", cfg.NomadRestartInterval, defaultRestartInterval),
+		RestartDelay:        getEnvOrConfig("NOMAD_RESTART_DELAY", cfg.NomadRestartDelay, defaultRestartDelay),
+		RescheduleAttempts:  getEnvOrConfigInt("NOMAD_RESCHEDULE_ATTEMPTS", rescheduleAttemptsConfig, defaultRescheduleAttempts),
+		RescheduleInterval:  getEnvOrConfig("NOMAD_RESCHEDULE_INTERVAL", cfg.NomadRescheduleInterval, defaultRescheduleInterval),
+		DisconnectLostAfter: getEnvOrConfig("NOMAD_DISCONNECT_LOST_AFTER", cfg.NomadDisconnectLostAfter, ""),
+		DisconnectStopAfter: getEnvOrConfig("NOMAD_DISCONNECT_STOP_AFTER", cfg.NomadDisconnectStopAfter, ""),
+		DisconnectReplace:   getEnvOrConfigBool("NOMAD_DISCONNECT_REPLACE", disconnectReplaceConfig, false),
+		KillTimeout:         getEnvOrConfig("NOMAD_KILL_TIMEOUT", cfg.NomadKillTimeout, defaultKillTimeout),
+		KillSignal:          getEnvOrConfig("NOMAD_KILL_SIGNAL", cfg.NomadKillSignal, defaultKillSignal),
+
+		MountDockerSocket: getEnvOrConfigBool("NOMAD_MOUNT_DOCKER_SOCKET", mountSocketConfig, true),
+		MountDockerCerts:  getEnvOrConfigBool("NOMAD_MOUNT_DOCKER_CERTS", mountCertsConfig, true),
+		MountRegistryCA:   getEnvOrConfigBool("NOMAD_MOUNT_REGISTRY_CA", mountCAConfig, true),
+		RegistryCAPath:    getEnvOrConfig("NOMAD_REGISTRY_CA_PATH", cfg.NomadRegistryCAPath, defaultRegistryCAPath),
+		ExtraVolumes:      extraVolumes,
+
+		RegistryAuth: registryAuth,
+		ForcePull:    getEnvOrConfigBool("NOMAD_FORCE_PULL", forcePullConfig, false),
 
 		// Vault configuration
-		VaultAddr:       getEnvOrConfig("VAULT_ADDR", cfg.VaultAddr, ""),
-		VaultRole:       getEnvOrConfig("VAULT_ROLE", cfg.VaultRole, defaultVaultRole),
-		VaultNamespace:  getEnvOrConfig("VAULT_NAMESPACE", cfg.VaultNamespace, ""),
-		VaultChangeMode: getEnvOrConfig("VAULT_CHANGE_MODE", cfg.VaultChangeMode, defaultVaultChangeMode),
-		VaultPolicies:   vaultPolicies,
-		VaultSecrets:    vaultSecrets,
+		VaultAddr:         getEnvOrConfig("VAULT_ADDR", cfg.VaultAddr, ""),
+		VaultRole:         getEnvOrConfig("VAULT_ROLE", cfg.VaultRole, defaultVaultRole),
+		VaultNamespace:    getEnvOrConfig("VAULT_NAMESPACE", cfg.VaultNamespace, ""),
+		VaultChangeMode:   getEnvOrConfig("VAULT_CHANGE_MODE", cfg.VaultChangeMode, defaultVaultChangeMode),
+		VaultChangeSignal: getEnvOrConfig("VAULT_CHANGE_SIGNAL", cfg.VaultChangeSignal, defaultVaultChangeSignal),
+		VaultPolicies:     vaultPolicies,
+		VaultSecrets:      vaultSecrets,
+		VaultAuthMethod:   getEnvOrConfig("VAULT_AUTH_METHOD", cfg.VaultAuthMethod, defaultVaultAuthMethod),
+		VaultAuthMount:    getEnvOrConfig("VAULT_AUTH_MOUNT", cfg.VaultAuthMount, ""),
+		VaultAuthRole:     getEnvOrConfig("VAULT_AUTH_ROLE", cfg.VaultAuthRole, ""),
+
+		// Nomad Variables configuration
+		NomadVariables: nomadVariables,
+
+		// Consul configuration
+		ConsulKV:       consulKV,
+		ConsulServices: consulServices,
+
+		Constraints: constraints,
+		Affinities:  affinities,
+		Spreads:     spreads,
+
+		Sidecars: sidecars,
+
+		JobTemplate: getJobTemplatePath(cfg.NomadJobTemplate, workspacePath),
 
 		// CSI Storage configuration
 		StorageMode:  getEnvOrConfig("NOMAD_STORAGE_MODE", cfg.NomadStorageMode, defaultStorageMode),
@@ -165,6 +446,26 @@ func DefaultOptions() (*Options, error) {
 		GPUEnabled:           gpuEnabled,
 		GPUCount:             gpuCount,
 		GPUComputeCapability: getEnvOrConfig("NOMAD_GPU_COMPUTE_CAPABILITY", gpuCapabilityConfig, ""),
+		GPUVendor:            getEnvOrConfig("NOMAD_GPU_VENDOR", cfg.NomadGPUVendor, defaultGPUVendor),
+		GPUMinMemoryMB:       gpuMinMemoryMB,
+		GPUModel:             getEnvOrConfig("NOMAD_GPU_MODEL", cfg.NomadGPUModel, ""),
+		GPUDriverVersion:     getEnvOrConfig("NOMAD_GPU_DRIVER_VERSION", cfg.NomadGPUDriverVersion, ""),
+		GPUPreferredModel:    getEnvOrConfig("NOMAD_GPU_PREFERRED_MODEL", cfg.NomadGPUPreferredModel, ""),
+		GPUPreferredMemoryMB: gpuPreferredMemoryMB,
+
+		GPUArch:               getEnvOrConfig("NOMAD_GPU_ARCH", cfg.NomadGPUArch, defaultGPUArch),
+		GPUDedicated:          getEnvOrConfig("NOMAD_GPU_DEDICATED", cfg.NomadGPUDedicated, defaultGPUDedicated),
+		GPUShmSizeMB:          gpuShmSizeMB,
+		GPUDriverCapabilities: getEnvOrConfig("NOMAD_GPU_DRIVER_CAPABILITIES", cfg.NomadGPUDriverCapabilities, defaultGPUDriverCapabilities),
+	}
+
+	// Validate the job ID, which names the workspace directory
+	if err := opts.ValidateJobID(); err != nil {
+		return nil, err
+	}
+	if opts.AgentDataPath != "" && !strings.HasPrefix(opts.AgentDataPath, opts.WorkspacePath()+"/") {
+		logger := log.Default.ErrorStreamOnly()
+		logger.Warnf("AGENT_DATA_PATH %s is outside the workspace directory %s; devcontainers may not see their files and secrets are not copied into them", opts.AgentDataPath, opts.WorkspacePath())
 	}
 
 	// Validate Vault configuration
@@ -172,6 +473,66 @@ func DefaultOptions() (*Options, error) {
 		return nil, err
 	}
 
+	// Validate Nomad Variables configuration
+	if err := opts.ValidateNomadVariables(); err != nil {
+		return nil, err
+	}
+
+	// Validate Consul configuration
+	if err := opts.ValidateConsul(); err != nil {
+		return nil, err
+	}
+
+	// Validate job placement
+	if err := opts.ValidatePlacement(); err != nil {
+		return nil, err
+	}
+
+	// Validate task driver
+	if err := opts.ValidateTaskDriver(); err != nil {
+		return nil, err
+	}
+
+	// Validate bootstrap mode
+	if err := opts.ValidateBootstrap(); err != nil {
+		return nil, err
+	}
+
+	// Validate networking
+	if err := opts.ValidateNetwork(); err != nil {
+		return nil, err
+	}
+
+	// Validate service registration
+	if err := opts.ValidateServices(); err != nil {
+		return nil, err
+	}
+
+	// Validate restart, reschedule and disconnect settings
+	if err := opts.ValidateScheduling(); err != nil {
+		return nil, err
+	}
+
+	// Validate host mounts
+	if err := opts.ValidateMounts(); err != nil {
+		return nil, err
+	}
+
+	// Validate private registry credentials
+	if err := opts.ValidateRegistryAuth(); err != nil {
+		return nil, err
+	}
+
+	// Validate sidecar tasks
+	if err := opts.ValidateSidecars(); err != nil {
+		return nil, err
+	}
+
+	// Validate the devcontainer run options
+	if err := opts.ValidateRunOptions(); err != nil {
+		return nil, err
+	}
+
 	// Validate CSI configuration
 	if err := opts.ValidateCSI(); err != nil {
 		return nil, err
@@ -185,6 +546,16 @@ func DefaultOptions() (*Options, error) {
 	return opts, nil
 }
 
+// getJobTemplatePath returns the job template path from env var or config file.
+// Relative paths are resolved against the workspace, next to .devpod/nomad.yaml.
+func getJobTemplatePath(configValue, workspacePath string) string {
+	path := getEnvOrConfig("NOMAD_JOB_TEMPLATE", configValue, "")
+	if path != "" && workspacePath != "" && !filepath.IsAbs(path) {
+		return filepath.Join(workspacePath, path)
+	}
+	return path
+}
+
 func getEnv(key, fallback string) string {
 	if value, ok := os.LookupEnv(key); ok {
 		return value
@@ -233,8 +604,79 @@ func getVaultSecrets(configFile *ConfigFile) ([]VaultSecret, error) {
 	return nil, nil
 }
 
+// getNomadVariables returns Nomad Variables from env var (JSON) or config file.
+// Environment variable takes precedence.
+func getNomadVariables(configFile *ConfigFile) ([]NomadVariable, error) {
+	// Check env var first
+	nomadVariablesJSON := os.Getenv("NOMAD_VARIABLES_JSON")
+	if nomadVariablesJSON != "" {
+		var variables []NomadVariable
+		if err := json.Unmarshal([]byte(nomadVariablesJSON), &variables); err != nil {
+			return nil, fmt.Errorf("unmarshal NOMAD_VARIABLES_JSON: %w", err)
+		}
+		return variables, nil
+	}
+
+	// Fall back to config file
+	if configFile != nil && len(configFile.NomadVariables) > 0 {
+		return configFile.NomadVariables, nil
+	}
+
+	return nil, nil
+}
+
+// getConsulKV returns Consul KV mappings from env var (JSON) or config file.
+// Environment variable takes precedence.
+func getConsulKV(configFile *ConfigFile) ([]ConsulKV, error) {
+	consulKVJSON := os.Getenv("CONSUL_KV_JSON")
+	if consulKVJSON != "" {
+		var kvs []ConsulKV
+		if err := json.Unmarshal([]byte(consulKVJSON), &kvs); err != nil {
+			return nil, fmt.Errorf("unmarshal CONSUL_KV_JSON: %w", err)
+		}
+		return kvs, nil
+	}
+
+	if configFile != nil && len(configFile.ConsulKV) > 0 {
+		return configFile.ConsulKV, nil
+	}
+
+	return nil, nil
+}
+
+// getConsulServices returns Consul service mappings from env var (JSON) or config file.
+// Environment variable takes precedence.
+func getConsulServices(configFile *ConfigFile) ([]ConsulService, error) {
+	consulServicesJSON := os.Getenv("CONSUL_SERVICES_JSON")
+	if consulServicesJSON != "" {
+		var services []ConsulService
+		if err := json.Unmarshal([]byte(consulServicesJSON), &services); err != nil {
+			return nil, fmt.Errorf("unmarshal CONSUL_SERVICES_JSON: %w", err)
+		}
+		return services, nil
+	}
+
+	if configFile != nil && len(configFile.ConsulServices) > 0 {
+		return configFile.ConsulServices, nil
+	}
+
+	return nil, nil
+}
+
 // ValidateVault validates Vault configuration settings
 func (o *Options) ValidateVault() error {
+	// Validate the provider-side auth method, which is independent of task secrets
+	validAuthMethods := map[string]bool{
+		"token":      true,
+		"token_file": true,
+		"approle":    true,
+		"jwt":        true,
+		"nomad":      true,
+	}
+	if !validAuthMethods[o.VaultAuthMethod] {
+		return fmt.Errorf("invalid VAULT_AUTH_METHOD: %s (must be token, token_file, approle, jwt, or nomad)", o.VaultAuthMethod)
+	}
+
 	// If no Vault secrets configured, nothing to validate
 	if len(o.VaultSecrets) == 0 {
 		return nil
@@ -268,16 +710,138 @@ func (o *Options) ValidateVault() error {
 				return fmt.Errorf("vault secret at index %d (%s) has empty environment variable name for field %s", i, secret.Path, vaultField)
 			}
 		}
+
+		// Validate per-secret template settings
+		if secret.ChangeMode != "" {
+			if err := validateChangeMode("change_mode", secret.ChangeMode); err != nil {
+				return fmt.Errorf("vault secret at index %d (%s): %w", i, secret.Path, err)
+			}
+		}
+		if o.SecretChangeMode(secret) == "signal" {
+			if err := validateChangeSignal(o.SecretChangeSignal(secret)); err != nil {
+				return fmt.Errorf("vault secret at index %d (%s): %w", i, secret.Path, err)
+			}
+		}
+		if secret.Splay != "" {
+			splay, err := time.ParseDuration(secret.Splay)
+			if err != nil || splay < 0 {
+				return fmt.Errorf("vault secret at index %d (%s) has invalid splay %q (must be a duration like '30s')", i, secret.Path, secret.Splay)
+			}
+		}
+	}
+
+	return o.validateSharedChangeMode()
+}
+
+// SecretChangeMode returns the change mode for a secret, falling back to VAULT_CHANGE_MODE
+func (o *Options) SecretChangeMode(secret VaultSecret) string {
+	if secret.ChangeMode != "" {
+		return secret.ChangeMode
+	}
+	return o.VaultChangeMode
+}
+
+// SecretChangeSignal returns the change signal for a secret, falling back to VAULT_CHANGE_SIGNAL
+func (o *Options) SecretChangeSignal(secret VaultSecret) string {
+	if secret.ChangeSignal != "" {
+		return secret.ChangeSignal
+	}
+	return o.VaultChangeSignal
+}
+
+// ValidateNomadVariables validates Nomad Variables configuration settings
+func (o *Options) ValidateNomadVariables() error {
+	if len(o.NomadVariables) == 0 {
+		return nil
+	}
+
+	for i, variable := range o.NomadVariables {
+		if variable.Path == "" {
+			return fmt.Errorf("nomad variable at index %d has empty path", i)
+		}
+
+		if len(variable.Fields) == 0 {
+			return fmt.Errorf("nomad variable at index %d (%s) has no field mappings", i, variable.Path)
+		}
+
+		for item, envVar := range variable.Fields {
+			if item == "" {
+				return fmt.Errorf("nomad variable at index %d (%s) has empty item name", i, variable.Path)
+			}
+			if envVar == "" {
+				return fmt.Errorf("nomad variable at index %d (%s) has empty environment variable name for item %s", i, variable.Path, item)
+			}
+		}
+	}
+
+	// Nomad Variable templates share the Vault change mode
+	return o.validateSharedChangeMode()
+}
+
+// ValidateConsul validates Consul KV and service configuration settings
+func (o *Options) ValidateConsul() error {
+	if len(o.ConsulKV) == 0 && len(o.ConsulServices) == 0 {
+		return nil
+	}
+
+	for i, kv := range o.ConsulKV {
+		if kv.Key == "" {
+			return fmt.Errorf("consul kv at index %d has empty key", i)
+		}
+		if kv.Env == "" {
+			return fmt.Errorf("consul kv at index %d (%s) has empty environment variable name", i, kv.Key)
+		}
+	}
+
+	for i, service := range o.ConsulServices {
+		if service.Name == "" {
+			return fmt.Errorf("consul service at index %d has empty name", i)
+		}
+		if service.AddressEnv == "" && service.PortEnv == "" {
+			return fmt.Errorf("consul service at index %d (%s) needs address_env or port_env", i, service.Name)
+		}
 	}
 
-	// Validate change mode
+	// Consul templates share the Vault change mode
+	return o.validateSharedChangeMode()
+}
+
+// validateSharedChangeMode validates the global change mode and signal used by
+// templates without their own settings
+func (o *Options) validateSharedChangeMode() error {
+	if err := validateChangeMode("VAULT_CHANGE_MODE", o.VaultChangeMode); err != nil {
+		return err
+	}
+	if o.VaultChangeMode == "signal" {
+		return validateChangeSignal(o.VaultChangeSignal)
+	}
+	return nil
+}
+
+// validateChangeSignal validates a template change signal. The workspace bootstrap
+// handles these signals by refreshing the secrets files; other signals would stop it.
+func validateChangeSignal(signal string) error {
+	validSignals := map[string]bool{
+		"SIGHUP":  true,
+		"SIGUSR1": true,
+		"SIGUSR2": true,
+	}
+	if !validSignals[signal] {
+		return fmt.Errorf("invalid change signal: %q (must be SIGHUP, SIGUSR1, or SIGUSR2)", signal)
+	}
+	return nil
+}
+
+// validateChangeMode validates the change mode applied to secret templates, set by
+// the named setting
+func validateChangeMode(name, changeMode string) error {
 	validChangeModes := map[string]bool{
 		"restart": true,
 		"noop":    true,
 		"signal":  true,
 	}
-	if !validChangeModes[o.VaultChangeMode] {
-		return fmt.Errorf("invalid VAULT_CHANGE_MODE: %s (must be restart, noop, or signal)", o.VaultChangeMode)
+	if !validChangeModes[changeMode] {
+		return fmt.Errorf("invalid %s: %s (must be restart, noop, or signal)", name, changeMode)
 	}
 
 	return nil
@@ -309,17 +873,49 @@ func (o *Options) ValidateCSI() error {
 	return nil
 }
 
+// ValidateTaskDriver validates the task driver of the workspace task
+func (o *Options) ValidateTaskDriver() error {
+	switch o.TaskDriver {
+	case "", TaskDriverDocker, TaskDriverPodman, TaskDriverSysbox:
+		return nil
+	default:
+		return fmt.Errorf("invalid NOMAD_TASK_DRIVER: %s (must be %s, %s or %s)", o.TaskDriver, TaskDriverDocker, TaskDriverPodman, TaskDriverSysbox)
+	}
+}
+
 // ValidateGPU validates GPU configuration settings
 func (o *Options) ValidateGPU() error {
 	if !o.GPUEnabled {
 		return nil
 	}
 
+	// GPU passthrough relies on Docker runtimes and device options
+	if o.TaskDriver != "" && o.TaskDriver != TaskDriverDocker {
+		return fmt.Errorf("NOMAD_GPU requires NOMAD_TASK_DRIVER=%s (got %s)", TaskDriverDocker, o.TaskDriver)
+	}
+
 	if o.GPUCount < 1 {
 		return fmt.Errorf("NOMAD_GPU_COUNT must be at least 1")
 	}
 
+	if strings.Contains(o.GPUVendor, "/") {
+		// Raw device names are <vendor>/<type>[/<name>], e.g. "nvidia/gpu/A100-MIG-1g.10gb"
+		parts := strings.Split(o.GPUVendor, "/")
+		if len(parts) > 3 || slices.Contains(parts, "") {
+			return fmt.Errorf("invalid NOMAD_GPU_VENDOR: %s (device name must be <vendor>/<type>[/<name>])", o.GPUVendor)
+		}
+	}
+	switch o.GPUVendorName() {
+	case GPUVendorNVIDIA, GPUVendorAMD, GPUVendorIntel:
+	default:
+		return fmt.Errorf("invalid NOMAD_GPU_VENDOR: %s (must be nvidia, amd, intel, or a device name from one of them)", o.GPUVendor)
+	}
+
 	if o.GPUComputeCapability != "" {
+		if o.GPUVendorName() != GPUVendorNVIDIA {
+			return fmt.Errorf("NOMAD_GPU_COMPUTE_CAPABILITY is only supported for nvidia GPUs")
+		}
+
 		// Validate format X.Y
 		parts := strings.Split(o.GPUComputeCapability, ".")
 		if len(parts) != 2 {
@@ -332,10 +928,113 @@ func (o *Options) ValidateGPU() error {
 		}
 	}
 
+	if o.GPUMinMemoryMB < 0 {
+		return fmt.Errorf("NOMAD_GPU_MIN_MEMORY_MB must not be negative")
+	}
+	if o.GPUPreferredMemoryMB < 0 {
+		return fmt.Errorf("NOMAD_GPU_PREFERRED_MEMORY_MB must not be negative")
+	}
+
+	if o.GPUModel != "" {
+		if _, err := regexp.Compile(o.GPUModel); err != nil {
+			return fmt.Errorf("invalid NOMAD_GPU_MODEL: %s (must be a regular expression): %w", o.GPUModel, err)
+		}
+	}
+	if o.GPUPreferredModel != "" {
+		if _, err := regexp.Compile(o.GPUPreferredModel); err != nil {
+			return fmt.Errorf("invalid NOMAD_GPU_PREFERRED_MODEL: %s (must be a regular expression): %w", o.GPUPreferredModel, err)
+		}
+	}
+
+	if o.GPUDriverVersion != "" {
+		// Comma-separated version constraints, e.g. ">= 535.0, < 560"
+		for _, c := range strings.Split(o.GPUDriverVersion, ",") {
+			if !versionConstraintRegexp.MatchString(strings.TrimSpace(c)) {
+				return fmt.Errorf("invalid NOMAD_GPU_DRIVER_VERSION: %s (must be a version constraint like '>= 535.0')", o.GPUDriverVersion)
+			}
+		}
+	}
+
+	if o.GPUArch != "" && !archRegexp.MatchString(o.GPUArch) {
+		return fmt.Errorf("invalid NOMAD_GPU_ARCH: %s (must be any or a CPU architecture like amd64 or arm64)", o.GPUArch)
+	}
+
+	switch o.GPUDedicated {
+	case "", GPUDedicatedAny, GPUDedicatedExclude, GPUDedicatedRequire:
+	default:
+		return fmt.Errorf("invalid NOMAD_GPU_DEDICATED: %s (must be any, exclude, or require)", o.GPUDedicated)
+	}
+
+	if o.GPUShmSizeMB < 0 {
+		return fmt.Errorf("NOMAD_GPU_SHM_SIZE_MB must not be negative")
+	}
+
+	if o.GPUDriverCapabilities != "" {
+		validCapabilities := map[string]bool{
+			"all":      true,
+			"compute":  true,
+			"compat32": true,
+			"display":  true,
+			"graphics": true,
+			"utility":  true,
+			"video":    true,
+		}
+		for _, capability := range strings.Split(o.GPUDriverCapabilities, ",") {
+			if !validCapabilities[strings.TrimSpace(capability)] {
+				return fmt.Errorf("invalid NOMAD_GPU_DRIVER_CAPABILITIES: %s (must be a comma-separated list of all, compute, compat32, display, graphics, utility, video)", o.GPUDriverCapabilities)
+			}
+		}
+	}
+
 	return nil
 }
 
+// archRegexp matches CPU architecture names as fingerprinted by Nomad (attr.cpu.arch)
+var archRegexp = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// versionConstraintRegexp matches a single constraint of Nomad's version operand
+var versionConstraintRegexp = regexp.MustCompile(`^(=|!=|>|<|>=|<=|~>)?\s*v?[0-9]+(\.[0-9]+)*$`)
+
+// GPUDeviceName returns the Nomad device name requested for GPU workspaces
+func (o *Options) GPUDeviceName() string {
+	if strings.Contains(o.GPUVendor, "/") {
+		return o.GPUVendor
+	}
+	vendor := o.GPUVendor
+	if vendor == "" {
+		vendor = defaultGPUVendor
+	}
+	return vendor + "/gpu"
+}
+
+// GPUVendorName returns the vendor of the requested GPU device
+func (o *Options) GPUVendorName() string {
+	vendor, _, _ := strings.Cut(o.GPUDeviceName(), "/")
+	return vendor
+}
+
 // GetVolumeID returns the CSI volume ID for this workspace
 func (o *Options) GetVolumeID() string {
 	return "devpod-" + o.JobId
 }
+
+// WorkspacePath returns the workspace's own directory holding the DevPod agent and
+// workspace content. With the client's Docker socket it is bind-mounted from the same
+// path on the client, so each workspace only sees its own directory.
+func (o *Options) WorkspacePath() string {
+	return workspaceHostRoot + "/" + o.JobId
+}
+
+// UsesHostDocker reports whether the workspace uses the client's Docker daemon,
+// in which case WorkspacePath() is bind-mounted from the client
+func (o *Options) UsesHostDocker() bool {
+	return (o.TaskDriver == "" || o.TaskDriver == TaskDriverDocker) && o.MountDockerSocket
+}
+
+// ValidateJobID validates the job ID, which is also used as a directory name
+func (o *Options) ValidateJobID() error {
+	if o.JobId == "" || o.JobId == "." || o.JobId == ".." || strings.ContainsAny(o.JobId, "/\\ '\"\x00") {
+		return fmt.Errorf("invalid MACHINE_ID: %q (must be a valid directory name)", o.JobId)
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package options
 
 import (
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -126,6 +127,69 @@ func TestDefaultOptions_StorageMode(t *testing.T) {
 	}
 }
 
+func TestWorkspacePath(t *testing.T) {
+	opts := &Options{JobId: "devpod-abc"}
+	if got := opts.WorkspacePath(); got != "/tmp/devpod-workspaces/devpod-abc" {
+		t.Errorf("Expected per-job workspace path, got %s", got)
+	}
+}
+
+func TestValidateJobID(t *testing.T) {
+	opts := &Options{JobId: "devpod-abc"}
+	if err := opts.ValidateJobID(); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	for _, id := range []string{"", ".", "..", "a/b", "../etc", "a b", "a\\b"} {
+		opts := &Options{JobId: id}
+		if err := opts.ValidateJobID(); err == nil {
+			t.Errorf("Expected error for job ID %q", id)
+		}
+	}
+}
+
+func TestUsesHostDocker(t *testing.T) {
+	tests := []struct {
+		driver string
+		mount  bool
+		want   bool
+	}{
+		{TaskDriverDocker, true, true},
+		{TaskDriverDocker, false, false},
+		{TaskDriverPodman, true, false},
+		{TaskDriverSysbox, true, false},
+	}
+	for _, tt := range tests {
+		opts := &Options{TaskDriver: tt.driver, MountDockerSocket: tt.mount}
+		if got := opts.UsesHostDocker(); got != tt.want {
+			t.Errorf("UsesHostDocker() with driver %s and socket mount %v = %v, want %v", tt.driver, tt.mount, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTaskDriver(t *testing.T) {
+	for _, driver := range []string{TaskDriverDocker, TaskDriverPodman, TaskDriverSysbox} {
+		opts := &Options{TaskDriver: driver}
+		if err := opts.ValidateTaskDriver(); err != nil {
+			t.Errorf("Expected no error for task driver %s, got: %v", driver, err)
+		}
+	}
+
+	opts := &Options{TaskDriver: "exec"}
+	if err := opts.ValidateTaskDriver(); err == nil {
+		t.Error("Expected error for unsupported task driver")
+	}
+}
+
+func TestValidateGPU_RequiresDockerDriver(t *testing.T) {
+	for _, driver := range []string{TaskDriverPodman, TaskDriverSysbox} {
+		opts := &Options{GPUEnabled: true, GPUCount: 1, TaskDriver: driver}
+		if err := opts.ValidateGPU(); err == nil {
+			t.Errorf("Expected error for GPU with task driver %s", driver)
+		}
+	}
+}
+
 func TestValidateGPU_Disabled(t *testing.T) {
 	opts := &Options{
 		GPUEnabled: false,
@@ -214,6 +278,101 @@ func TestValidateGPU_InvalidCount(t *testing.T) {
 	}
 }
 
+func TestValidateGPU_Vendors(t *testing.T) {
+	tests := []struct {
+		vendor     string
+		capability string
+		wantErr    bool
+	}{
+		{"nvidia", "8.0", false},
+		{"amd", "", false},
+		{"intel", "", false},
+		{"nvidia/gpu/A100-MIG-1g.10gb", "8.0", false},
+		{"amd/gpu", "", false},
+		{"amd", "8.0", true},
+		{"qualcomm", "", true},
+		{"acme/gpu", "", true},
+		{"nvidia//A100", "", true},
+		{"nvidia/gpu/A100/extra", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.vendor, func(t *testing.T) {
+			opts := &Options{
+				GPUEnabled:           true,
+				GPUCount:             1,
+				GPUVendor:            tt.vendor,
+				GPUComputeCapability: tt.capability,
+			}
+
+			err := opts.ValidateGPU()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateGPU() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateGPU_DeviceFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		wantErr bool
+	}{
+		{"all filters", Options{GPUMinMemoryMB: 16384, GPUModel: "A100|H100", GPUDriverVersion: ">= 535.0, < 560", GPUPreferredModel: "H100", GPUPreferredMemoryMB: 81920}, false},
+		{"exact driver version", Options{GPUDriverVersion: "550.54.15"}, false},
+		{"negative memory", Options{GPUMinMemoryMB: -1}, true},
+		{"negative preferred memory", Options{GPUPreferredMemoryMB: -1}, true},
+		{"invalid model regexp", Options{GPUModel: "A100("}, true},
+		{"invalid preferred model regexp", Options{GPUPreferredModel: "[H100"}, true},
+		{"invalid driver version", Options{GPUDriverVersion: "latest"}, true},
+		{"invalid driver operator", Options{GPUDriverVersion: "=> 535"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.options
+			opts.GPUEnabled = true
+			opts.GPUCount = 1
+
+			err := opts.ValidateGPU()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateGPU() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateGPU_PlacementAndContainerSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		wantErr bool
+	}{
+		{"defaults", Options{GPUArch: "amd64", GPUDedicated: "exclude", GPUShmSizeMB: 2048, GPUDriverCapabilities: "compute,utility"}, false},
+		{"arm64 dedicated", Options{GPUArch: "arm64", GPUDedicated: "require"}, false},
+		{"any", Options{GPUArch: "any", GPUDedicated: "any"}, false},
+		{"all driver capabilities", Options{GPUDriverCapabilities: "all"}, false},
+		{"invalid arch", Options{GPUArch: "x86 64"}, true},
+		{"invalid dedicated policy", Options{GPUDedicated: "prefer"}, true},
+		{"negative shm size", Options{GPUShmSizeMB: -1}, true},
+		{"invalid driver capability", Options{GPUDriverCapabilities: "compute,cuda"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.options
+			opts.GPUEnabled = true
+			opts.GPUCount = 1
+
+			err := opts.ValidateGPU()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateGPU() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestDefaultOptions_GPU(t *testing.T) {
 	// Save current environment
 	origGPU := os.Getenv("NOMAD_GPU")
@@ -224,6 +383,8 @@ func TestDefaultOptions_GPU(t *testing.T) {
 	os.Unsetenv("NOMAD_GPU")
 	os.Unsetenv("NOMAD_GPU_COUNT")
 	os.Unsetenv("NOMAD_GPU_COMPUTE_CAPABILITY")
+	t.Setenv("NOMAD_GPU_VENDOR", "")
+	os.Unsetenv("NOMAD_GPU_VENDOR")
 
 	// Restore environment after test
 	defer func() {
@@ -260,6 +421,10 @@ func TestDefaultOptions_GPU(t *testing.T) {
 	if opts.GPUComputeCapability != "" {
 		t.Errorf("Expected default GPU compute capability to be empty, got %s", opts.GPUComputeCapability)
 	}
+
+	if opts.GPUVendor != GPUVendorNVIDIA {
+		t.Errorf("Expected default GPU vendor %s, got %s", GPUVendorNVIDIA, opts.GPUVendor)
+	}
 }
 
 func TestDefaultOptions_GPUEnabled(t *testing.T) {
@@ -298,3 +463,264 @@ func TestDefaultOptions_GPUEnabled(t *testing.T) {
 		t.Errorf("Expected GPU count 2, got %d", opts.GPUCount)
 	}
 }
+
+func TestValidateVault_AuthMethods(t *testing.T) {
+	for _, method := range []string{"token", "token_file", "approle", "jwt", "nomad"} {
+		opts := &Options{
+			VaultAuthMethod: method,
+		}
+
+		if err := opts.ValidateVault(); err != nil {
+			t.Errorf("Expected no error for auth method %s, got: %v", method, err)
+		}
+	}
+}
+
+func TestValidateVault_InvalidAuthMethod(t *testing.T) {
+	opts := &Options{
+		VaultAuthMethod: "ldap",
+	}
+
+	err := opts.ValidateVault()
+	if err == nil {
+		t.Error("Expected error for invalid auth method")
+	}
+}
+
+func TestValidateVault_PerSecretSettings(t *testing.T) {
+	opts := &Options{
+		VaultAuthMethod:   "token",
+		VaultAddr:         "https://vault.example.com:8200",
+		VaultPolicies:     []string{"devpod"},
+		VaultChangeMode:   "restart",
+		VaultChangeSignal: "SIGHUP",
+		VaultSecrets: []VaultSecret{
+			{Path: "secret/data/db", Fields: map[string]string{"password": "DB_PASSWORD"}},
+			{Path: "secret/data/api", Fields: map[string]string{"key": "API_KEY"}, ChangeMode: "signal", ChangeSignal: "SIGUSR1", Splay: "30s"},
+			{Path: "secret/data/aws", Fields: map[string]string{"key": "AWS_KEY"}, ChangeMode: "noop"},
+		},
+	}
+
+	if err := opts.ValidateVault(); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+}
+
+func TestValidateVault_InvalidPerSecretSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  VaultSecret
+		wantErr string
+	}{
+		{"invalid change mode", VaultSecret{ChangeMode: "reload"}, "vault secret at index 0 (secret/data/test): invalid change_mode: reload"},
+		{"unsupported signal", VaultSecret{ChangeMode: "signal", ChangeSignal: "SIGTERM"}, "invalid change signal"},
+		{"invalid splay", VaultSecret{Splay: "soon"}, "invalid splay"},
+		{"negative splay", VaultSecret{Splay: "-5s"}, "invalid splay"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.secret.Path = "secret/data/test"
+			tt.secret.Fields = map[string]string{"key": "KEY"}
+			opts := &Options{
+				VaultAuthMethod:   "token",
+				VaultAddr:         "https://vault.example.com:8200",
+				VaultPolicies:     []string{"devpod"},
+				VaultChangeMode:   "restart",
+				VaultChangeSignal: "SIGHUP",
+				VaultSecrets:      []VaultSecret{tt.secret},
+			}
+
+			err := opts.ValidateVault()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSecretChangeSettings_FallBackToGlobal(t *testing.T) {
+	opts := &Options{
+		VaultChangeMode:   "signal",
+		VaultChangeSignal: "SIGUSR2",
+	}
+
+	secret := VaultSecret{Path: "secret/data/test"}
+	if opts.SecretChangeMode(secret) != "signal" || opts.SecretChangeSignal(secret) != "SIGUSR2" {
+		t.Errorf("Expected global settings, got %s/%s", opts.SecretChangeMode(secret), opts.SecretChangeSignal(secret))
+	}
+
+	secret = VaultSecret{Path: "secret/data/test", ChangeMode: "noop", ChangeSignal: "SIGHUP"}
+	if opts.SecretChangeMode(secret) != "noop" || opts.SecretChangeSignal(secret) != "SIGHUP" {
+		t.Errorf("Expected per-secret settings, got %s/%s", opts.SecretChangeMode(secret), opts.SecretChangeSignal(secret))
+	}
+}
+
+func TestValidateNomadVariables_Valid(t *testing.T) {
+	opts := &Options{
+		VaultChangeMode: "restart",
+		NomadVariables: []NomadVariable{
+			{Path: "nomad/jobs/devpod/db", Fields: map[string]string{"password": "DB_PASSWORD"}},
+		},
+	}
+
+	err := opts.ValidateNomadVariables()
+	if err != nil {
+		t.Errorf("Expected no error for valid Nomad Variables, got: %v", err)
+	}
+}
+
+func TestValidateNomadVariables_EmptyPath(t *testing.T) {
+	opts := &Options{
+		VaultChangeMode: "restart",
+		NomadVariables: []NomadVariable{
+			{Path: "", Fields: map[string]string{"password": "DB_PASSWORD"}},
+		},
+	}
+
+	err := opts.ValidateNomadVariables()
+	if err == nil {
+		t.Error("Expected error for Nomad Variable with empty path")
+	}
+}
+
+func TestValidateNomadVariables_NoFields(t *testing.T) {
+	opts := &Options{
+		VaultChangeMode: "restart",
+		NomadVariables: []NomadVariable{
+			{Path: "nomad/jobs/devpod/db"},
+		},
+	}
+
+	err := opts.ValidateNomadVariables()
+	if err == nil {
+		t.Error("Expected error for Nomad Variable without field mappings")
+	}
+}
+
+func TestValidateNomadVariables_EmptyEnvVar(t *testing.T) {
+	opts := &Options{
+		VaultChangeMode: "restart",
+		NomadVariables: []NomadVariable{
+			{Path: "nomad/jobs/devpod/db", Fields: map[string]string{"password": ""}},
+		},
+	}
+
+	err := opts.ValidateNomadVariables()
+	if err == nil {
+		t.Error("Expected error for Nomad Variable with empty environment variable name")
+	}
+}
+
+func TestGetNomadVariables_EnvTakesPrecedence(t *testing.T) {
+	t.Setenv("NOMAD_VARIABLES_JSON", `[{"path":"nomad/jobs/env","fields":{"token":"TOKEN"}}]`)
+	configFile := &ConfigFile{
+		NomadVariables: []NomadVariable{
+			{Path: "nomad/jobs/config", Fields: map[string]string{"token": "TOKEN"}},
+		},
+	}
+
+	variables, err := getNomadVariables(configFile)
+	if err != nil {
+		t.Fatalf("getNomadVariables failed: %v", err)
+	}
+	if len(variables) != 1 || variables[0].Path != "nomad/jobs/env" {
+		t.Errorf("Expected variables from NOMAD_VARIABLES_JSON, got %v", variables)
+	}
+}
+
+func TestGetNomadVariables_InvalidJSON(t *testing.T) {
+	t.Setenv("NOMAD_VARIABLES_JSON", `[{"path":`)
+
+	_, err := getNomadVariables(nil)
+	if err == nil {
+		t.Error("Expected error for invalid NOMAD_VARIABLES_JSON")
+	}
+}
+
+func TestValidateConsul_Valid(t *testing.T) {
+	opts := &Options{
+		VaultChangeMode: "restart",
+		ConsulKV:        []ConsulKV{{Key: "dev/postgres/database", Env: "DB_NAME"}},
+		ConsulServices:  []ConsulService{{Name: "postgres", AddressEnv: "DB_HOST"}},
+	}
+
+	err := opts.ValidateConsul()
+	if err != nil {
+		t.Errorf("Expected no error for valid Consul config, got: %v", err)
+	}
+}
+
+func TestValidateConsul_EmptyKey(t *testing.T) {
+	opts := &Options{
+		VaultChangeMode: "restart",
+		ConsulKV:        []ConsulKV{{Key: "", Env: "DB_NAME"}},
+	}
+
+	err := opts.ValidateConsul()
+	if err == nil {
+		t.Error("Expected error for Consul KV with empty key")
+	}
+}
+
+func TestValidateConsul_EmptyEnv(t *testing.T) {
+	opts := &Options{
+		VaultChangeMode: "restart",
+		ConsulKV:        []ConsulKV{{Key: "dev/postgres/database"}},
+	}
+
+	err := opts.ValidateConsul()
+	if err == nil {
+		t.Error("Expected error for Consul KV with empty environment variable name")
+	}
+}
+
+func TestValidateConsul_ServiceWithoutEnv(t *testing.T) {
+	opts := &Options{
+		VaultChangeMode: "restart",
+		ConsulServices:  []ConsulService{{Name: "postgres"}},
+	}
+
+	err := opts.ValidateConsul()
+	if err == nil {
+		t.Error("Expected error for Consul service without address_env or port_env")
+	}
+}
+
+func TestValidateConsul_ServiceWithoutName(t *testing.T) {
+	opts := &Options{
+		VaultChangeMode: "restart",
+		ConsulServices:  []ConsulService{{AddressEnv: "DB_HOST"}},
+	}
+
+	err := opts.ValidateConsul()
+	if err == nil {
+		t.Error("Expected error for Consul service with empty name")
+	}
+}
+
+func TestGetJobTemplatePath(t *testing.T) {
+	tests := []struct {
+		name          string
+		env           string
+		configValue   string
+		workspacePath string
+		want          string
+	}{
+		{name: "unset", workspacePath: "/src/project", want: ""},
+		{name: "relative config path", configValue: "nomad/job.hcl", workspacePath: "/src/project", want: "/src/project/nomad/job.hcl"},
+		{name: "absolute config path", configValue: "/etc/devpod/job.hcl", workspacePath: "/src/project", want: "/etc/devpod/job.hcl"},
+		{name: "env takes precedence", env: "job.json", configValue: "nomad/job.hcl", workspacePath: "/src/project", want: "/src/project/job.json"},
+		{name: "no workspace", configValue: "nomad/job.hcl", want: "nomad/job.hcl"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NOMAD_JOB_TEMPLATE", tt.env)
+
+			if got := getJobTemplatePath(tt.configValue, tt.workspacePath); got != tt.want {
+				t.Errorf("getJobTemplatePath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
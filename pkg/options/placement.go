@@ -0,0 +1,230 @@
+package options
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Constraint restricts the nodes a workspace job can be placed on
+type Constraint struct {
+	Attribute string `json:"attribute"`          // Node attribute (e.g., "${node.class}")
+	Operator  string `json:"operator,omitempty"` // Nomad constraint operator, defaults to "="
+	Value     string `json:"value"`              // Value compared against the attribute
+}
+
+// Affinity expresses a placement preference for a workspace job
+type Affinity struct {
+	Attribute string `json:"attribute"`          // Node attribute (e.g., "${meta.rack}")
+	Operator  string `json:"operator,omitempty"` // Nomad affinity operator, defaults to "="
+	Value     string `json:"value"`              // Value compared against the attribute
+	Weight    int    `json:"weight"`             // -100 to 100, negative values avoid matching nodes
+}
+
+// Spread distributes workspace jobs across the values of a node attribute
+type Spread struct {
+	Attribute string         `json:"attribute"`         // Node attribute (e.g., "${node.datacenter}")
+	Weight    int            `json:"weight,omitempty"`  // 1 to 100, defaults to Nomad's default
+	Targets   []SpreadTarget `json:"targets,omitempty"` // Optional target percentages
+}
+
+// SpreadTarget is the desired share of jobs for one attribute value
+type SpreadTarget struct {
+	Value   string `json:"value"`
+	Percent int    `json:"percent"`
+}
+
+// Operators accepted in constraints, see https://developer.hashicorp.com/nomad/docs/job-specification/constraint
+var validConstraintOperators = map[string]bool{
+	"=": true, "==": true, "is": true, "!=": true, "not": true,
+	">": true, ">=": true, "<": true, "<=": true,
+	"regexp": true, "version": true, "semver": true,
+	"set_contains": true, "set_contains_all": true, "set_contains_any": true,
+	"is_set": true, "is_not_set": true,
+	"distinct_hosts": true, "distinct_property": true,
+}
+
+// Operators accepted in affinities, see https://developer.hashicorp.com/nomad/docs/job-specification/affinity
+var validAffinityOperators = map[string]bool{
+	"=": true, "==": true, "is": true, "!=": true, "not": true,
+	">": true, ">=": true, "<": true, "<=": true,
+	"regexp": true, "version": true, "semver": true,
+	"set_contains_all": true, "set_contains_any": true,
+}
+
+// nodePoolNameRegexp matches valid Nomad node pool names
+var nodePoolNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9-_]{1,128}$`)
+
+// getDatacenters returns the job datacenters from env var (comma-separated) or config file.
+// Environment variable takes precedence.
+func getDatacenters(configFile *ConfigFile) []string {
+	if datacentersEnv := os.Getenv("NOMAD_DATACENTERS"); datacentersEnv != "" {
+		var datacenters []string
+		for _, dc := range strings.Split(datacentersEnv, ",") {
+			datacenters = append(datacenters, strings.TrimSpace(dc))
+		}
+		return datacenters
+	}
+
+	if configFile != nil && len(configFile.NomadDatacenters) > 0 {
+		return configFile.NomadDatacenters
+	}
+
+	return nil
+}
+
+// getPriority returns the job priority from env var or config file.
+// Environment variable takes precedence; the range is checked in ValidatePlacement.
+func getPriority(configFile *ConfigFile) (int, error) {
+	if priorityEnv := os.Getenv("NOMAD_PRIORITY"); priorityEnv != "" {
+		priority, err := strconv.Atoi(priorityEnv)
+		if err != nil {
+			return 0, fmt.Errorf("invalid NOMAD_PRIORITY: %s (must be a number between 1 and 100)", priorityEnv)
+		}
+		return priority, nil
+	}
+
+	if configFile != nil && configFile.NomadPriority != nil {
+		return *configFile.NomadPriority, nil
+	}
+
+	return defaultPriority, nil
+}
+
+// getConstraints returns job constraints from env var (JSON) or config file.
+// Environment variable takes precedence.
+func getConstraints(configFile *ConfigFile) ([]Constraint, error) {
+	constraintsJSON := os.Getenv("NOMAD_CONSTRAINTS_JSON")
+	if constraintsJSON != "" {
+		var constraints []Constraint
+		if err := json.Unmarshal([]byte(constraintsJSON), &constraints); err != nil {
+			return nil, fmt.Errorf("unmarshal NOMAD_CONSTRAINTS_JSON: %w", err)
+		}
+		return constraints, nil
+	}
+
+	if configFile != nil && len(configFile.NomadConstraints) > 0 {
+		return configFile.NomadConstraints, nil
+	}
+
+	return nil, nil
+}
+
+// getAffinities returns job affinities from env var (JSON) or config file.
+// Environment variable takes precedence.
+func getAffinities(configFile *ConfigFile) ([]Affinity, error) {
+	affinitiesJSON := os.Getenv("NOMAD_AFFINITIES_JSON")
+	if affinitiesJSON != "" {
+		var affinities []Affinity
+		if err := json.Unmarshal([]byte(affinitiesJSON), &affinities); err != nil {
+			return nil, fmt.Errorf("unmarshal NOMAD_AFFINITIES_JSON: %w", err)
+		}
+		return affinities, nil
+	}
+
+	if configFile != nil && len(configFile.NomadAffinities) > 0 {
+		return configFile.NomadAffinities, nil
+	}
+
+	return nil, nil
+}
+
+// getSpreads returns job spreads from env var (JSON) or config file.
+// Environment variable takes precedence.
+func getSpreads(configFile *ConfigFile) ([]Spread, error) {
+	spreadJSON := os.Getenv("NOMAD_SPREAD_JSON")
+	if spreadJSON != "" {
+		var spreads []Spread
+		if err := json.Unmarshal([]byte(spreadJSON), &spreads); err != nil {
+			return nil, fmt.Errorf("unmarshal NOMAD_SPREAD_JSON: %w", err)
+		}
+		return spreads, nil
+	}
+
+	if configFile != nil && len(configFile.NomadSpread) > 0 {
+		return configFile.NomadSpread, nil
+	}
+
+	return nil, nil
+}
+
+// ValidatePlacement validates the datacenters, node pool, priority and
+// user-defined constraints, affinities and spreads
+func (o *Options) ValidatePlacement() error {
+	for i, dc := range o.Datacenters {
+		if dc == "" {
+			return fmt.Errorf("invalid NOMAD_DATACENTERS: empty datacenter at index %d", i)
+		}
+	}
+
+	if o.NodePool != "" && !nodePoolNameRegexp.MatchString(o.NodePool) {
+		return fmt.Errorf("invalid NOMAD_NODE_POOL: %s (must be up to 128 letters, digits, '-' or '_')", o.NodePool)
+	}
+
+	// Zero means unset and leaves the priority to Nomad
+	if o.Priority != 0 && (o.Priority < 1 || o.Priority > 100) {
+		return fmt.Errorf("invalid NOMAD_PRIORITY: %d (must be between 1 and 100)", o.Priority)
+	}
+
+	for i, c := range o.Constraints {
+		operator := c.Operator
+		if operator == "" {
+			operator = "="
+		}
+		if !validConstraintOperators[operator] {
+			return fmt.Errorf("constraint at index %d has invalid operator: %s", i, c.Operator)
+		}
+		// distinct_hosts applies to the whole job and takes no attribute
+		if c.Attribute == "" && operator != "distinct_hosts" {
+			return fmt.Errorf("constraint at index %d has empty attribute", i)
+		}
+		// Set checks and distinct_hosts have no value to compare against
+		needsValue := operator != "is_set" && operator != "is_not_set" &&
+			operator != "distinct_hosts" && operator != "distinct_property"
+		if needsValue && c.Value == "" {
+			return fmt.Errorf("constraint at index %d (%s) has empty value", i, c.Attribute)
+		}
+	}
+
+	for i, a := range o.Affinities {
+		if a.Attribute == "" {
+			return fmt.Errorf("affinity at index %d has empty attribute", i)
+		}
+		if a.Operator != "" && !validAffinityOperators[a.Operator] {
+			return fmt.Errorf("affinity at index %d (%s) has invalid operator: %s", i, a.Attribute, a.Operator)
+		}
+		if a.Value == "" {
+			return fmt.Errorf("affinity at index %d (%s) has empty value", i, a.Attribute)
+		}
+		if a.Weight == 0 || a.Weight < -100 || a.Weight > 100 {
+			return fmt.Errorf("affinity at index %d (%s) has invalid weight: %d (must be between -100 and 100, not 0)", i, a.Attribute, a.Weight)
+		}
+	}
+
+	for i, s := range o.Spreads {
+		if s.Attribute == "" {
+			return fmt.Errorf("spread at index %d has empty attribute", i)
+		}
+		if s.Weight < 0 || s.Weight > 100 {
+			return fmt.Errorf("spread at index %d (%s) has invalid weight: %d (must be between 1 and 100)", i, s.Attribute, s.Weight)
+		}
+		total := 0
+		for _, target := range s.Targets {
+			if target.Value == "" {
+				return fmt.Errorf("spread at index %d (%s) has a target with empty value", i, s.Attribute)
+			}
+			if target.Percent < 0 || target.Percent > 100 {
+				return fmt.Errorf("spread at index %d (%s) has invalid percent for %s: %d", i, s.Attribute, target.Value, target.Percent)
+			}
+			total += target.Percent
+		}
+		if total > 100 {
+			return fmt.Errorf("spread at index %d (%s) has target percentages summing to %d (must be at most 100)", i, s.Attribute, total)
+		}
+	}
+
+	return nil
+}
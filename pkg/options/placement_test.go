@@ -0,0 +1,176 @@
+package options
+
+import (
+	"testing"
+)
+
+func TestValidatePlacement(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		wantErr bool
+	}{
+		{
+			name:    "empty",
+			options: Options{},
+		},
+		{
+			name: "valid placement",
+			options: Options{
+				Constraints: []Constraint{
+					{Attribute: "${node.class}", Value: "workspace"},
+					{Attribute: "${meta.role}", Operator: "!=", Value: "build-agent"},
+					{Attribute: "${meta.ssd}", Operator: "is_set"},
+					{Operator: "distinct_hosts"},
+				},
+				Affinities: []Affinity{{Attribute: "${meta.rack}", Operator: "regexp", Value: "r1.*", Weight: -30}},
+				Spreads: []Spread{
+					{Attribute: "${node.datacenter}"},
+					{Attribute: "${meta.zone}", Weight: 80, Targets: []SpreadTarget{{Value: "a", Percent: 70}, {Value: "b", Percent: 30}}},
+				},
+			},
+		},
+		{
+			name:    "datacenters and node pool",
+			options: Options{Datacenters: []string{"dc1", "dc2"}, NodePool: "gpu-workspaces"},
+		},
+		{
+			name:    "empty datacenter",
+			options: Options{Datacenters: []string{"dc1", ""}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid node pool",
+			options: Options{NodePool: "gpu pool"},
+			wantErr: true,
+		},
+		{
+			name:    "priority bounds",
+			options: Options{Priority: 100},
+		},
+		{
+			name:    "priority too high",
+			options: Options{Priority: 101},
+			wantErr: true,
+		},
+		{
+			name:    "negative priority",
+			options: Options{Priority: -1},
+			wantErr: true,
+		},
+		{
+			name:    "constraint invalid operator",
+			options: Options{Constraints: []Constraint{{Attribute: "${node.class}", Operator: "~", Value: "x"}}},
+			wantErr: true,
+		},
+		{
+			name:    "constraint empty attribute",
+			options: Options{Constraints: []Constraint{{Value: "workspace"}}},
+			wantErr: true,
+		},
+		{
+			name:    "constraint empty value",
+			options: Options{Constraints: []Constraint{{Attribute: "${node.class}", Operator: "regexp"}}},
+			wantErr: true,
+		},
+		{
+			name:    "affinity set_contains is constraint only",
+			options: Options{Affinities: []Affinity{{Attribute: "${meta.tags}", Operator: "set_contains", Value: "a", Weight: 50}}},
+			wantErr: true,
+		},
+		{
+			name:    "affinity zero weight",
+			options: Options{Affinities: []Affinity{{Attribute: "${meta.rack}", Value: "r1"}}},
+			wantErr: true,
+		},
+		{
+			name:    "affinity weight out of range",
+			options: Options{Affinities: []Affinity{{Attribute: "${meta.rack}", Value: "r1", Weight: 150}}},
+			wantErr: true,
+		},
+		{
+			name:    "spread empty attribute",
+			options: Options{Spreads: []Spread{{Weight: 50}}},
+			wantErr: true,
+		},
+		{
+			name:    "spread targets over 100 percent",
+			options: Options{Spreads: []Spread{{Attribute: "${meta.zone}", Targets: []SpreadTarget{{Value: "a", Percent: 70}, {Value: "b", Percent: 40}}}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.options.ValidatePlacement()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePlacement() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetConstraints_EnvTakesPrecedence(t *testing.T) {
+	t.Setenv("NOMAD_CONSTRAINTS_JSON", `[{"attribute":"${node.class}","value":"env"}]`)
+	configFile := &ConfigFile{
+		NomadConstraints: []Constraint{{Attribute: "${node.class}", Value: "config"}},
+	}
+
+	constraints, err := getConstraints(configFile)
+	if err != nil {
+		t.Fatalf("getConstraints failed: %v", err)
+	}
+	if len(constraints) != 1 || constraints[0].Value != "env" {
+		t.Errorf("Expected constraint from env, got %v", constraints)
+	}
+}
+
+func TestGetSpreads_InvalidJSON(t *testing.T) {
+	t.Setenv("NOMAD_SPREAD_JSON", `{"attribute":`)
+
+	if _, err := getSpreads(nil); err == nil {
+		t.Error("Expected error for invalid NOMAD_SPREAD_JSON")
+	}
+}
+
+func TestGetDatacenters(t *testing.T) {
+	configFile := &ConfigFile{NomadDatacenters: []string{"config-dc"}}
+
+	t.Setenv("NOMAD_DATACENTERS", "")
+	if dcs := getDatacenters(configFile); len(dcs) != 1 || dcs[0] != "config-dc" {
+		t.Errorf("Expected datacenters from config, got %v", dcs)
+	}
+
+	t.Setenv("NOMAD_DATACENTERS", "dc1, dc2")
+	if dcs := getDatacenters(configFile); len(dcs) != 2 || dcs[0] != "dc1" || dcs[1] != "dc2" {
+		t.Errorf("Expected datacenters from env, got %v", dcs)
+	}
+
+	t.Setenv("NOMAD_DATACENTERS", "")
+	if dcs := getDatacenters(nil); dcs != nil {
+		t.Errorf("Expected no datacenters, got %v", dcs)
+	}
+}
+
+func TestGetPriority(t *testing.T) {
+	configPriority := 30
+	configFile := &ConfigFile{NomadPriority: &configPriority}
+
+	t.Setenv("NOMAD_PRIORITY", "")
+	if priority, err := getPriority(nil); err != nil || priority != defaultPriority {
+		t.Errorf("Expected default priority %d, got %d (%v)", defaultPriority, priority, err)
+	}
+	if priority, err := getPriority(configFile); err != nil || priority != 30 {
+		t.Errorf("Expected priority 30 from config, got %d (%v)", priority, err)
+	}
+
+	t.Setenv("NOMAD_PRIORITY", "80")
+	if priority, err := getPriority(configFile); err != nil || priority != 80 {
+		t.Errorf("Expected priority 80 from env, got %d (%v)", priority, err)
+	}
+
+	t.Setenv("NOMAD_PRIORITY", "high")
+	if _, err := getPriority(configFile); err == nil {
+		t.Error("Expected error for non-numeric NOMAD_PRIORITY")
+	}
+}
@@ -0,0 +1,87 @@
+package options
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// RegistryAuth holds the credentials the task driver pulls images from a private
+// registry with. Credentials are set inline or read from a Vault KV v2 secret
+// when the task starts.
+type RegistryAuth struct {
+	Registry  string `json:"registry"`                                       // Registry host, e.g. "ghcr.io" or "registry.example.com:5000"
+	Username  string `json:"username,omitempty"`                             // Username, required with password
+	Password  string `json:"password,omitempty"`                             // Password
+	Token     string `json:"token,omitempty"`                                // Access token, sent as the password
+	VaultPath string `json:"vault_path,omitempty" yaml:"vault_path"`         // Vault KV v2 path with username and password or token fields
+	SoftFail  bool   `json:"auth_soft_fail,omitempty" yaml:"auth_soft_fail"` // Pull without credentials if they can't be read
+}
+
+// getRegistryAuth returns registry credentials from env var (JSON) or config file.
+// Environment variable takes precedence.
+func getRegistryAuth(configFile *ConfigFile) ([]RegistryAuth, error) {
+	authJSON := os.Getenv("NOMAD_REGISTRY_AUTH_JSON")
+	if authJSON != "" {
+		var auths []RegistryAuth
+		if err := json.Unmarshal([]byte(authJSON), &auths); err != nil {
+			return nil, fmt.Errorf("unmarshal NOMAD_REGISTRY_AUTH_JSON: %w", err)
+		}
+		return auths, nil
+	}
+
+	if configFile != nil && len(configFile.RegistryAuth) > 0 {
+		return configFile.RegistryAuth, nil
+	}
+
+	return nil, nil
+}
+
+// RegistryAuthFromVault reports whether any registry credentials are read from Vault
+func (o *Options) RegistryAuthFromVault() bool {
+	for _, auth := range o.RegistryAuth {
+		if auth.VaultPath != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateRegistryAuth validates the private registry credentials
+func (o *Options) ValidateRegistryAuth() error {
+	registries := map[string]bool{}
+	for i, auth := range o.RegistryAuth {
+		// Images reference registries by host, so URLs would never match
+		if auth.Registry == "" || strings.Contains(auth.Registry, "/") {
+			return fmt.Errorf("registry auth at index %d has invalid registry: %q (must be a host such as ghcr.io)", i, auth.Registry)
+		}
+		if registries[auth.Registry] {
+			return fmt.Errorf("registry auth at index %d has duplicate registry: %s", i, auth.Registry)
+		}
+		registries[auth.Registry] = true
+
+		sources := 0
+		for _, set := range []bool{auth.Password != "", auth.Token != "", auth.VaultPath != ""} {
+			if set {
+				sources++
+			}
+		}
+		if sources != 1 {
+			return fmt.Errorf("registry auth for %s must set exactly one of password, token or vault_path", auth.Registry)
+		}
+		if auth.Password != "" && auth.Username == "" {
+			return fmt.Errorf("registry auth for %s requires a username with the password", auth.Registry)
+		}
+		if auth.VaultPath != "" && auth.Username != "" {
+			return fmt.Errorf("registry auth for %s reads the username from vault_path and can't set it", auth.Registry)
+		}
+	}
+
+	// Credentials are rendered with the task's Vault token
+	if o.RegistryAuthFromVault() && len(o.VaultPolicies) == 0 {
+		return fmt.Errorf("VAULT_POLICIES_JSON is required when a registry auth sets vault_path")
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package options
+
+import (
+	"testing"
+)
+
+func TestValidateRegistryAuth(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		wantErr bool
+	}{
+		{
+			name: "password, token and vault",
+			options: Options{
+				VaultPolicies: []string{"devpod"},
+				RegistryAuth: []RegistryAuth{
+					{Registry: "docker.io", Username: "acme", Password: "secret"},
+					{Registry: "ghcr.io", Token: "ghp_example"},
+					{Registry: "registry.example.com:5000", VaultPath: "secret/data/registry", SoftFail: true},
+				},
+			},
+		},
+		{
+			name:    "missing registry",
+			options: Options{RegistryAuth: []RegistryAuth{{Token: "ghp_example"}}},
+			wantErr: true,
+		},
+		{
+			name:    "registry URL",
+			options: Options{RegistryAuth: []RegistryAuth{{Registry: "https://ghcr.io", Token: "ghp_example"}}},
+			wantErr: true,
+		},
+		{
+			name: "duplicate registry",
+			options: Options{RegistryAuth: []RegistryAuth{
+				{Registry: "ghcr.io", Token: "ghp_example"},
+				{Registry: "ghcr.io", Token: "ghp_other"},
+			}},
+			wantErr: true,
+		},
+		{
+			name:    "no credentials",
+			options: Options{RegistryAuth: []RegistryAuth{{Registry: "ghcr.io", Username: "acme"}}},
+			wantErr: true,
+		},
+		{
+			name:    "password and token",
+			options: Options{RegistryAuth: []RegistryAuth{{Registry: "ghcr.io", Username: "acme", Password: "secret", Token: "ghp_example"}}},
+			wantErr: true,
+		},
+		{
+			name:    "password without username",
+			options: Options{RegistryAuth: []RegistryAuth{{Registry: "ghcr.io", Password: "secret"}}},
+			wantErr: true,
+		},
+		{
+			name: "vault with username",
+			options: Options{
+				VaultPolicies: []string{"devpod"},
+				RegistryAuth:  []RegistryAuth{{Registry: "ghcr.io", Username: "acme", VaultPath: "secret/data/registry"}},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "vault without policies",
+			options: Options{RegistryAuth: []RegistryAuth{{Registry: "ghcr.io", VaultPath: "secret/data/registry"}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.options.ValidateRegistryAuth()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRegistryAuth() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetRegistryAuth_EnvTakesPrecedence(t *testing.T) {
+	t.Setenv("NOMAD_REGISTRY_AUTH_JSON", `[{"registry":"ghcr.io","vault_path":"secret/data/registry","auth_soft_fail":true}]`)
+	configFile := &ConfigFile{
+		RegistryAuth: []RegistryAuth{{Registry: "docker.io", Token: "dckr_pat_example"}},
+	}
+
+	auths, err := getRegistryAuth(configFile)
+	if err != nil {
+		t.Fatalf("getRegistryAuth failed: %v", err)
+	}
+	if len(auths) != 1 || auths[0].Registry != "ghcr.io" || auths[0].VaultPath != "secret/data/registry" || !auths[0].SoftFail {
+		t.Errorf("Expected registry auth from env, got %v", auths)
+	}
+}
+
+func TestGetRegistryAuth_InvalidJSON(t *testing.T) {
+	t.Setenv("NOMAD_REGISTRY_AUTH_JSON", `[{"registry":`)
+
+	if _, err := getRegistryAuth(nil); err == nil {
+		t.Error("Expected error for invalid NOMAD_REGISTRY_AUTH_JSON")
+	}
+}
@@ -0,0 +1,75 @@
+package options
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/loft-sh/devpod/pkg/devcontainer/config"
+)
+
+// Devcontainer mount types DevPod passes in its run options
+const (
+	MountTypeBind   = "bind"   // Path on the Nomad client
+	MountTypeVolume = "volume" // Named volume that lives as long as the allocation
+)
+
+// RunMounts returns the workspace mount followed by the additional mounts of the
+// devcontainer run options
+func (o *Options) RunMounts() []*config.Mount {
+	if o.DriverOpts == nil {
+		return nil
+	}
+
+	var mounts []*config.Mount
+	if o.DriverOpts.WorkspaceMount != nil {
+		mounts = append(mounts, o.DriverOpts.WorkspaceMount)
+	}
+	for _, m := range o.DriverOpts.Mounts {
+		if m != nil {
+			mounts = append(mounts, m)
+		}
+	}
+	return mounts
+}
+
+// ValidateRunOptions validates the devcontainer settings DevPod passes in
+// DEVCONTAINER_RUN_OPTIONS against what the workspace task can provide
+func (o *Options) ValidateRunOptions() error {
+	if o.DriverOpts == nil {
+		return nil
+	}
+
+	// Sysbox isolates the container in a user namespace instead of running it privileged
+	if o.DriverOpts.Privileged != nil && *o.DriverOpts.Privileged && o.TaskDriver == TaskDriverSysbox {
+		return fmt.Errorf("devcontainer requests privileged mode, which the %s task driver does not support (use %s or %s)", TaskDriverSysbox, TaskDriverDocker, TaskDriverPodman)
+	}
+
+	for _, m := range o.RunMounts() {
+		switch m.Type {
+		case MountTypeBind:
+			if !path.IsAbs(m.Source) {
+				return fmt.Errorf("devcontainer bind mount %s has invalid source: %q (must be an absolute path on the Nomad client)", m.Target, m.Source)
+			}
+			// Bind sources are paths on the client, where the workspace directory only
+			// exists when it is bind-mounted into the task
+			if !o.UsesHostDocker() && inDir(m.Source, o.WorkspacePath()) {
+				return fmt.Errorf("devcontainer bind mount %s has source %s in the workspace directory, which only exists on the Nomad client with NOMAD_TASK_DRIVER=%s and NOMAD_MOUNT_DOCKER_SOCKET=true", m.Target, m.Source, TaskDriverDocker)
+			}
+		case "", MountTypeVolume:
+		default:
+			return fmt.Errorf("devcontainer mount %s has unsupported type: %s (must be %s or %s)", m.Target, m.Type, MountTypeBind, MountTypeVolume)
+		}
+		if !path.IsAbs(m.Target) {
+			return fmt.Errorf("devcontainer mount has invalid target: %q (must be an absolute path)", m.Target)
+		}
+	}
+
+	return nil
+}
+
+// inDir reports whether p is dir or a path below it
+func inDir(p, dir string) bool {
+	p = path.Clean(p)
+	return p == dir || strings.HasPrefix(p, dir+"/")
+}
@@ -0,0 +1,101 @@
+package options
+
+import (
+	"testing"
+
+	"github.com/loft-sh/devpod/pkg/devcontainer/config"
+	"github.com/loft-sh/devpod/pkg/driver"
+)
+
+func TestValidateRunOptions(t *testing.T) {
+	privileged := true
+	tests := []struct {
+		name    string
+		options Options
+		wantErr bool
+	}{
+		{
+			name:    "no run options",
+			options: Options{},
+		},
+		{
+			name: "bind and volume mounts",
+			options: Options{DriverOpts: &driver.RunOptions{
+				WorkspaceMount: &config.Mount{Type: "bind", Source: "/srv/src/project", Target: "/workspaces/project"},
+				Mounts: []*config.Mount{
+					{Type: "volume", Source: "go-cache", Target: "/go/pkg"},
+					{Source: "node-modules", Target: "/workspaces/project/node_modules"},
+				},
+			}},
+		},
+		{
+			name: "privileged docker",
+			options: Options{TaskDriver: TaskDriverDocker, DriverOpts: &driver.RunOptions{
+				Privileged: &privileged,
+			}},
+		},
+		{
+			name: "privileged sysbox",
+			options: Options{TaskDriver: TaskDriverSysbox, DriverOpts: &driver.RunOptions{
+				Privileged: &privileged,
+			}},
+			wantErr: true,
+		},
+		{
+			name: "tmpfs mount",
+			options: Options{DriverOpts: &driver.RunOptions{
+				Mounts: []*config.Mount{{Type: "tmpfs", Target: "/tmp/cache"}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "workspace directory bind mounted from the client",
+			options: Options{JobId: "ws", TaskDriver: TaskDriverDocker, MountDockerSocket: true, DriverOpts: &driver.RunOptions{
+				WorkspaceMount: &config.Mount{Type: "bind", Source: "/tmp/devpod-workspaces/ws/agent/content", Target: "/workspaces/project"},
+			}},
+		},
+		{
+			name: "workspace directory only in a nested docker task",
+			options: Options{JobId: "ws", TaskDriver: TaskDriverPodman, DriverOpts: &driver.RunOptions{
+				WorkspaceMount: &config.Mount{Type: "bind", Source: "/tmp/devpod-workspaces/ws/agent/content", Target: "/workspaces/project"},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "workspace directory without the docker socket",
+			options: Options{JobId: "ws", TaskDriver: TaskDriverDocker, DriverOpts: &driver.RunOptions{
+				Mounts: []*config.Mount{{Type: "bind", Source: "/tmp/devpod-workspaces/ws", Target: "/workspaces"}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "client path without the docker socket",
+			options: Options{JobId: "ws", TaskDriver: TaskDriverSysbox, DriverOpts: &driver.RunOptions{
+				Mounts: []*config.Mount{{Type: "bind", Source: "/tmp/devpod-workspaces/ws2", Target: "/datasets"}},
+			}},
+		},
+		{
+			name: "relative bind source",
+			options: Options{DriverOpts: &driver.RunOptions{
+				WorkspaceMount: &config.Mount{Type: "bind", Source: "project", Target: "/workspaces/project"},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "relative target",
+			options: Options{DriverOpts: &driver.RunOptions{
+				Mounts: []*config.Mount{{Type: "volume", Source: "cache", Target: "cache"}},
+			}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.options.ValidateRunOptions()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRunOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package options
+
+import (
+	"fmt"
+	"time"
+)
+
+// Workspace-oriented defaults: a failing bootstrap gives up instead of restarting
+// forever, and the workspace is never moved to another node, where ephemeral data
+// would be lost, unless rescheduling is enabled.
+const (
+	defaultRestartAttempts    = 2
+	defaultRestartInterval    = "30m"
+	defaultRestartDelay       = "15s"
+	defaultRescheduleAttempts = 0
+	defaultRescheduleInterval = "1h"
+	defaultKillTimeout        = "30s"
+	defaultKillSignal         = "SIGTERM"
+
+	// MinKillTimeout is the shortest kill timeout accepted, which leaves the bootstrap
+	// script time to stop the workspace command and start the final sync
+	MinKillTimeout = 5 * time.Second
+)
+
+// ValidateScheduling validates the restart, reschedule, disconnect and shutdown settings
+func (o *Options) ValidateScheduling() error {
+	if o.RestartAttempts < 0 {
+		return fmt.Errorf("invalid NOMAD_RESTART_ATTEMPTS: %d (must not be negative)", o.RestartAttempts)
+	}
+	if o.RescheduleAttempts < 0 {
+		return fmt.Errorf("invalid NOMAD_RESCHEDULE_ATTEMPTS: %d (must not be negative)", o.RescheduleAttempts)
+	}
+
+	durations := []struct {
+		name  string
+		value string
+	}{
+		{"NOMAD_RESTART_INTERVAL", o.RestartInterval},
+		{"NOMAD_RESTART_DELAY", o.RestartDelay},
+		{"NOMAD_RESCHEDULE_INTERVAL", o.RescheduleInterval},
+		{"NOMAD_DISCONNECT_LOST_AFTER", o.DisconnectLostAfter},
+		{"NOMAD_DISCONNECT_STOP_AFTER", o.DisconnectStopAfter},
+		{"NOMAD_KILL_TIMEOUT", o.KillTimeout},
+	}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		if v, err := time.ParseDuration(d.value); err != nil || v < 0 {
+			return fmt.Errorf("invalid %s: %q (must be a duration like '30s')", d.name, d.value)
+		}
+	}
+
+	// Nomad rejects restart policies that can't fit the attempts into the interval
+	if o.RestartAttempts > 0 && o.RestartInterval != "" && o.RestartDelay != "" {
+		interval, _ := time.ParseDuration(o.RestartInterval)
+		delay, _ := time.ParseDuration(o.RestartDelay)
+		if time.Duration(o.RestartAttempts)*delay > interval {
+			return fmt.Errorf("NOMAD_RESTART_INTERVAL %s is too short for %d restarts with a delay of %s", o.RestartInterval, o.RestartAttempts, o.RestartDelay)
+		}
+	}
+
+	if o.KillTimeout != "" {
+		timeout, _ := time.ParseDuration(o.KillTimeout)
+		if err := ValidateKillTimeout("NOMAD_KILL_TIMEOUT", timeout); err != nil {
+			return err
+		}
+	}
+	if err := ValidateKillSignal("NOMAD_KILL_SIGNAL", o.KillSignal); err != nil {
+		return err
+	}
+
+	// Nomad rejects disconnect blocks that set both
+	if o.DisconnectLostAfter != "" && o.DisconnectStopAfter != "" {
+		return fmt.Errorf("NOMAD_DISCONNECT_LOST_AFTER and NOMAD_DISCONNECT_STOP_AFTER can't be set together")
+	}
+
+	return nil
+}
+
+// ValidateKillSignal checks that signal, set by the named setting, stops the
+// workspace through the bootstrap script, which only flushes persistent data on the
+// signals it traps
+func ValidateKillSignal(name, signal string) error {
+	if signal != "SIGTERM" && signal != "SIGINT" {
+		return fmt.Errorf("invalid %s: %s (must be SIGTERM or SIGINT)", name, signal)
+	}
+	return nil
+}
+
+// ValidateKillTimeout checks that timeout, set by the named setting, is at least
+// MinKillTimeout
+func ValidateKillTimeout(name string, timeout time.Duration) error {
+	if timeout < MinKillTimeout {
+		return fmt.Errorf("invalid %s: %s (must be at least %s)", name, timeout, MinKillTimeout)
+	}
+	return nil
+}
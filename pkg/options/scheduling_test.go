@@ -0,0 +1,95 @@
+package options
+
+import (
+	"testing"
+)
+
+func TestValidateScheduling(t *testing.T) {
+	defaults := func() Options {
+		return Options{
+			RestartAttempts:    defaultRestartAttempts,
+			RestartInterval:    defaultRestartInterval,
+			RestartDelay:       defaultRestartDelay,
+			RescheduleAttempts: defaultRescheduleAttempts,
+			RescheduleInterval: defaultRescheduleInterval,
+			KillTimeout:        defaultKillTimeout,
+			KillSignal:         defaultKillSignal,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(o *Options)
+		wantErr bool
+	}{
+		{
+			name:   "defaults",
+			modify: func(o *Options) {},
+		},
+		{
+			name: "rescheduling and lost after",
+			modify: func(o *Options) {
+				o.RescheduleAttempts = 3
+				o.DisconnectLostAfter = "1h"
+				o.DisconnectReplace = true
+			},
+		},
+		{
+			name:    "negative restart attempts",
+			modify:  func(o *Options) { o.RestartAttempts = -1 },
+			wantErr: true,
+		},
+		{
+			name:    "negative reschedule attempts",
+			modify:  func(o *Options) { o.RescheduleAttempts = -1 },
+			wantErr: true,
+		},
+		{
+			name:    "invalid kill timeout",
+			modify:  func(o *Options) { o.KillTimeout = "30" },
+			wantErr: true,
+		},
+		{
+			name:    "kill timeout too short for the final sync",
+			modify:  func(o *Options) { o.KillTimeout = "1s" },
+			wantErr: true,
+		},
+		{
+			name:    "negative restart delay",
+			modify:  func(o *Options) { o.RestartDelay = "-15s" },
+			wantErr: true,
+		},
+		{
+			name:    "untrapped kill signal",
+			modify:  func(o *Options) { o.KillSignal = "SIGKILL" },
+			wantErr: true,
+		},
+		{
+			name: "restart interval too short",
+			modify: func(o *Options) {
+				o.RestartAttempts = 5
+				o.RestartInterval = "1m"
+			},
+			wantErr: true,
+		},
+		{
+			name: "lost after and stop after",
+			modify: func(o *Options) {
+				o.DisconnectLostAfter = "1h"
+				o.DisconnectStopAfter = "10m"
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := defaults()
+			tt.modify(&opts)
+			err := opts.ValidateScheduling()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateScheduling() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package options
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"regexp"
+	"strings"
+)
+
+// Service registration providers
+const (
+	ServiceProviderNomad  = "nomad"
+	ServiceProviderConsul = "consul"
+)
+
+// Ingress routers the service tags are generated for
+const (
+	ServiceRoutingTraefik = "traefik"
+	ServiceRoutingFabio   = "fabio"
+)
+
+// Nomad and Consul only accept service names that are valid DNS labels
+var serviceNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// getServiceTags returns the service tags from env var (JSON) or config file.
+// Environment variable takes precedence.
+func getServiceTags(configFile *ConfigFile) ([]string, error) {
+	tagsJSON := os.Getenv("NOMAD_SERVICE_TAGS_JSON")
+	if tagsJSON != "" {
+		var tags []string
+		if err := json.Unmarshal([]byte(tagsJSON), &tags); err != nil {
+			return nil, fmt.Errorf("unmarshal NOMAD_SERVICE_TAGS_JSON: %w", err)
+		}
+		return tags, nil
+	}
+
+	if configFile != nil && len(configFile.NomadServiceTags) > 0 {
+		return configFile.NomadServiceTags, nil
+	}
+
+	return nil, nil
+}
+
+// defaultOwner returns the local user running DevPod
+func defaultOwner() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return getEnv("USER", "")
+}
+
+// ServiceName returns the name of the service registered for the port with the given label
+func (o *Options) ServiceName(label string) string {
+	return strings.ToLower(o.JobId + "-" + strings.ReplaceAll(label, "_", "-"))
+}
+
+// ValidateServices validates the service registration and routing settings
+func (o *Options) ValidateServices() error {
+	switch o.ServiceProvider {
+	case "":
+		if o.ServiceRouting != "" {
+			return fmt.Errorf("NOMAD_SERVICE_ROUTING requires NOMAD_SERVICE_PROVIDER")
+		}
+		return nil
+	case ServiceProviderNomad, ServiceProviderConsul:
+	default:
+		return fmt.Errorf("invalid NOMAD_SERVICE_PROVIDER: %s (must be %s or %s)", o.ServiceProvider, ServiceProviderNomad, ServiceProviderConsul)
+	}
+
+	if len(o.Ports) == 0 {
+		return fmt.Errorf("NOMAD_SERVICE_PROVIDER requires at least one port in NOMAD_PORTS_JSON")
+	}
+	for _, p := range o.Ports {
+		if name := o.ServiceName(p.Label); !serviceNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid service name for port %s: %s (must be a valid DNS label of at most 63 characters)", p.Label, name)
+		}
+	}
+
+	switch o.ServiceRouting {
+	case "":
+	case ServiceRoutingTraefik, ServiceRoutingFabio:
+		if o.ServiceDomain == "" {
+			return fmt.Errorf("NOMAD_SERVICE_ROUTING %s requires NOMAD_SERVICE_DOMAIN", o.ServiceRouting)
+		}
+	default:
+		return fmt.Errorf("invalid NOMAD_SERVICE_ROUTING: %s (must be %s or %s)", o.ServiceRouting, ServiceRoutingTraefik, ServiceRoutingFabio)
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package options
+
+import (
+	"testing"
+)
+
+func TestValidateServices(t *testing.T) {
+	ports := []Port{{Label: "http", To: 3000}}
+
+	tests := []struct {
+		name    string
+		options Options
+		wantErr bool
+	}{
+		{
+			name:    "disabled",
+			options: Options{JobId: "devpod-test", Ports: ports},
+		},
+		{
+			name:    "nomad provider",
+			options: Options{JobId: "devpod-test", Ports: ports, ServiceProvider: ServiceProviderNomad},
+		},
+		{
+			name: "consul provider with traefik routing",
+			options: Options{JobId: "devpod-test", Ports: ports, ServiceProvider: ServiceProviderConsul,
+				ServiceRouting: ServiceRoutingTraefik, ServiceDomain: "dev.example.com"},
+		},
+		{
+			name:    "invalid provider",
+			options: Options{JobId: "devpod-test", Ports: ports, ServiceProvider: "etcd"},
+			wantErr: true,
+		},
+		{
+			name:    "provider without ports",
+			options: Options{JobId: "devpod-test", ServiceProvider: ServiceProviderNomad},
+			wantErr: true,
+		},
+		{
+			name:    "routing without provider",
+			options: Options{JobId: "devpod-test", Ports: ports, ServiceRouting: ServiceRoutingFabio, ServiceDomain: "dev.example.com"},
+			wantErr: true,
+		},
+		{
+			name:    "routing without domain",
+			options: Options{JobId: "devpod-test", Ports: ports, ServiceProvider: ServiceProviderNomad, ServiceRouting: ServiceRoutingFabio},
+			wantErr: true,
+		},
+		{
+			name:    "invalid routing",
+			options: Options{JobId: "devpod-test", Ports: ports, ServiceProvider: ServiceProviderNomad, ServiceRouting: "nginx"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid service name",
+			options: Options{JobId: "devpod.test", Ports: ports, ServiceProvider: ServiceProviderNomad},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.options.ValidateServices()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateServices() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServiceName(t *testing.T) {
+	opts := &Options{JobId: "DevPod-Test"}
+	if got := opts.ServiceName("api_server"); got != "devpod-test-api-server" {
+		t.Errorf("Expected devpod-test-api-server, got %s", got)
+	}
+}
+
+func TestGetServiceTags_EnvTakesPrecedence(t *testing.T) {
+	t.Setenv("NOMAD_SERVICE_TAGS_JSON", `["owner={owner}"]`)
+	configFile := &ConfigFile{
+		NomadServiceTags: []string{"devpod"},
+	}
+
+	tags, err := getServiceTags(configFile)
+	if err != nil {
+		t.Fatalf("getServiceTags failed: %v", err)
+	}
+	if len(tags) != 1 || tags[0] != "owner={owner}" {
+		t.Errorf("Expected tags from env, got %v", tags)
+	}
+}
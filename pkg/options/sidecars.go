@@ -0,0 +1,77 @@
+package options
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Sidecar lifecycle hooks
+const (
+	SidecarLifecyclePrestart  = "prestart"
+	SidecarLifecyclePoststart = "poststart"
+)
+
+// Sidecar is an additional container (e.g., a database or cache) run next to the
+// workspace in the same task group, reachable from the workspace on localhost
+type Sidecar struct {
+	Name      string            `json:"name"`                                 // Task name, unique within the workspace
+	Image     string            `json:"image"`                                // Docker image (e.g., "postgres:16")
+	Command   string            `json:"command,omitempty"`                    // Optional command replacing the image CMD
+	Args      []string          `json:"args,omitempty"`                       // Optional arguments
+	Env       map[string]string `json:"env,omitempty"`                        // Environment variables
+	CPU       int               `json:"cpu,omitempty"`                        // CPU in MHz, defaults to 100
+	MemoryMB  int               `json:"memory_mb,omitempty" yaml:"memory_mb"` // Memory in MB, defaults to 256
+	Lifecycle string            `json:"lifecycle,omitempty"`                  // prestart (default) or poststart
+}
+
+// getSidecars returns sidecars from env var (JSON) or config file.
+// Environment variable takes precedence.
+func getSidecars(configFile *ConfigFile) ([]Sidecar, error) {
+	sidecarsJSON := os.Getenv("NOMAD_SIDECARS_JSON")
+	if sidecarsJSON != "" {
+		var sidecars []Sidecar
+		if err := json.Unmarshal([]byte(sidecarsJSON), &sidecars); err != nil {
+			return nil, fmt.Errorf("unmarshal NOMAD_SIDECARS_JSON: %w", err)
+		}
+		return sidecars, nil
+	}
+
+	if configFile != nil && len(configFile.Sidecars) > 0 {
+		return configFile.Sidecars, nil
+	}
+
+	return nil, nil
+}
+
+// ValidateSidecars validates the sidecar tasks
+func (o *Options) ValidateSidecars() error {
+	names := map[string]bool{}
+	for i, s := range o.Sidecars {
+		if s.Name == "" {
+			return fmt.Errorf("sidecar at index %d has empty name", i)
+		}
+		// Task names become directory names in the allocation
+		if strings.ContainsAny(s.Name, "/\\\x00") {
+			return fmt.Errorf("sidecar at index %d has invalid name: %s", i, s.Name)
+		}
+		if s.Name == o.TaskName || names[s.Name] {
+			return fmt.Errorf("sidecar at index %d has duplicate name: %s", i, s.Name)
+		}
+		names[s.Name] = true
+
+		if s.Image == "" {
+			return fmt.Errorf("sidecar at index %d (%s) has empty image", i, s.Name)
+		}
+		if s.CPU < 0 || s.MemoryMB < 0 {
+			return fmt.Errorf("sidecar at index %d (%s) has negative resources", i, s.Name)
+		}
+		if s.Lifecycle != "" && s.Lifecycle != SidecarLifecyclePrestart && s.Lifecycle != SidecarLifecyclePoststart {
+			return fmt.Errorf("sidecar at index %d (%s) has invalid lifecycle: %s (must be %s or %s)",
+				i, s.Name, s.Lifecycle, SidecarLifecyclePrestart, SidecarLifecyclePoststart)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package options
+
+import (
+	"testing"
+)
+
+func TestValidateSidecars(t *testing.T) {
+	tests := []struct {
+		name     string
+		sidecars []Sidecar
+		wantErr  bool
+	}{
+		{
+			name: "valid sidecars",
+			sidecars: []Sidecar{
+				{Name: "postgres", Image: "postgres:16", MemoryMB: 512},
+				{Name: "redis", Image: "redis:7", Lifecycle: SidecarLifecyclePoststart},
+			},
+		},
+		{
+			name:     "empty name",
+			sidecars: []Sidecar{{Image: "redis:7"}},
+			wantErr:  true,
+		},
+		{
+			name:     "invalid name",
+			sidecars: []Sidecar{{Name: "cache/redis", Image: "redis:7"}},
+			wantErr:  true,
+		},
+		{
+			name:     "duplicate name",
+			sidecars: []Sidecar{{Name: "redis", Image: "redis:7"}, {Name: "redis", Image: "redis:6"}},
+			wantErr:  true,
+		},
+		{
+			name:     "workspace task name",
+			sidecars: []Sidecar{{Name: "devpod", Image: "redis:7"}},
+			wantErr:  true,
+		},
+		{
+			name:     "empty image",
+			sidecars: []Sidecar{{Name: "redis"}},
+			wantErr:  true,
+		},
+		{
+			name:     "negative memory",
+			sidecars: []Sidecar{{Name: "redis", Image: "redis:7", MemoryMB: -1}},
+			wantErr:  true,
+		},
+		{
+			name:     "invalid lifecycle",
+			sidecars: []Sidecar{{Name: "redis", Image: "redis:7", Lifecycle: "poststop"}},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &Options{TaskName: "devpod", Sidecars: tt.sidecars}
+			err := opts.ValidateSidecars()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSidecars() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetSidecars_EnvTakesPrecedence(t *testing.T) {
+	t.Setenv("NOMAD_SIDECARS_JSON", `[{"name":"redis","image":"redis:7","memory_mb":128}]`)
+	configFile := &ConfigFile{
+		Sidecars: []Sidecar{{Name: "postgres", Image: "postgres:16"}},
+	}
+
+	sidecars, err := getSidecars(configFile)
+	if err != nil {
+		t.Fatalf("getSidecars failed: %v", err)
+	}
+	if len(sidecars) != 1 || sidecars[0].Name != "redis" || sidecars[0].MemoryMB != 128 {
+		t.Errorf("Expected sidecar from env, got %v", sidecars)
+	}
+}
+
+func TestGetSidecars_InvalidJSON(t *testing.T) {
+	t.Setenv("NOMAD_SIDECARS_JSON", `[{"name":`)
+
+	if _, err := getSidecars(nil); err == nil {
+		t.Error("Expected error for invalid NOMAD_SIDECARS_JSON")
+	}
+}
@@ -0,0 +1,194 @@
+package vault
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Supported methods for the provider to authenticate to Vault
+const (
+	AuthMethodToken     = "token"
+	AuthMethodTokenFile = "token_file"
+	AuthMethodAppRole   = "approle"
+	AuthMethodJWT       = "jwt"
+	AuthMethodNomad     = "nomad"
+)
+
+// Default auth mount paths for the login based methods
+const (
+	defaultAppRoleMount = "approle"
+	defaultJWTMount     = "jwt"
+	defaultNomadMount   = "jwt-nomad"
+)
+
+// AuthConfig describes how the provider obtains a Vault token.
+// Credentials are never part of the config; they are read from the environment
+// (or the token file) at login time.
+type AuthConfig struct {
+	Method    string // One of the AuthMethod* constants, defaults to "token"
+	Mount     string // Auth mount path, defaults depend on the method
+	Role      string // Role to log in with (jwt and nomad methods)
+	TokenFile string // Token file path for token_file, defaults to ~/.vault-token
+}
+
+// tokenCache holds tokens obtained during this process so repeated Vault
+// operations within one provider command only log in once.
+var (
+	tokenCacheMu sync.Mutex
+	tokenCache   = map[string]string{}
+)
+
+// Login authenticates the client using the configured method and sets the
+// resulting token on the client. Tokens are cached for the lifetime of the process.
+func (c *Client) Login(auth AuthConfig) error {
+	method := auth.Method
+	if method == "" {
+		method = AuthMethodToken
+	}
+
+	key := strings.Join([]string{c.client.Address(), c.client.Namespace(), method, auth.Mount, auth.Role}, "|")
+
+	tokenCacheMu.Lock()
+	defer tokenCacheMu.Unlock()
+
+	if token, ok := tokenCache[key]; ok {
+		c.client.SetToken(token)
+		return nil
+	}
+
+	var token string
+	var err error
+	switch method {
+	case AuthMethodToken:
+		token = GetTokenFromEnv()
+		if token == "" {
+			return fmt.Errorf("DEVPOD_VAULT_TOKEN environment variable is required for VAULT_AUTH_METHOD=token")
+		}
+	case AuthMethodTokenFile:
+		token, err = readTokenFile(auth.TokenFile)
+	case AuthMethodAppRole:
+		token, err = c.loginAppRole(auth)
+	case AuthMethodJWT:
+		token, err = c.loginJWT(auth)
+	case AuthMethodNomad:
+		token, err = c.loginNomad(auth)
+	default:
+		return fmt.Errorf("unsupported Vault auth method: %s", method)
+	}
+	if err != nil {
+		return err
+	}
+
+	tokenCache[key] = token
+	c.client.SetToken(token)
+	return nil
+}
+
+// readTokenFile reads a token written by "vault login" (~/.vault-token by default)
+func readTokenFile(path string) (string, error) {
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to determine home directory for Vault token file: %w", err)
+		}
+		path = filepath.Join(home, ".vault-token")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read Vault token file %s: %w", path, err)
+	}
+
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("vault token file %s is empty", path)
+	}
+	return token, nil
+}
+
+// loginAppRole logs in with DEVPOD_VAULT_ROLE_ID and DEVPOD_VAULT_SECRET_ID
+func (c *Client) loginAppRole(auth AuthConfig) (string, error) {
+	roleID := os.Getenv("DEVPOD_VAULT_ROLE_ID")
+	if roleID == "" {
+		return "", fmt.Errorf("DEVPOD_VAULT_ROLE_ID environment variable is required for VAULT_AUTH_METHOD=approle")
+	}
+
+	data := map[string]interface{}{
+		"role_id": roleID,
+	}
+	// The secret ID is optional when the role does not bind it
+	if secretID := os.Getenv("DEVPOD_VAULT_SECRET_ID"); secretID != "" {
+		data["secret_id"] = secretID
+	}
+
+	return c.login(mountOrDefault(auth.Mount, defaultAppRoleMount), data)
+}
+
+// loginJWT logs in with a JWT or OIDC ID token taken from DEVPOD_VAULT_JWT,
+// e.g. the ID token issued to a CI job
+func (c *Client) loginJWT(auth AuthConfig) (string, error) {
+	jwt := strings.TrimSpace(os.Getenv("DEVPOD_VAULT_JWT"))
+	if jwt == "" {
+		return "", fmt.Errorf("DEVPOD_VAULT_JWT environment variable is required for VAULT_AUTH_METHOD=jwt")
+	}
+
+	return c.login(mountOrDefault(auth.Mount, defaultJWTMount), jwtLoginData(jwt, auth.Role))
+}
+
+// loginNomad logs in with the Nomad workload identity of the task the provider
+// runs in. The identity is read from NOMAD_TOKEN_vault_default (identity with
+// env = true) or from ${NOMAD_SECRETS_DIR}/nomad_vault_default.jwt (file = true).
+func (c *Client) loginNomad(auth AuthConfig) (string, error) {
+	jwt := strings.TrimSpace(os.Getenv("NOMAD_TOKEN_vault_default"))
+	if jwt == "" {
+		secretsDir := os.Getenv("NOMAD_SECRETS_DIR")
+		if secretsDir == "" {
+			return "", fmt.Errorf("no Nomad workload identity found: NOMAD_TOKEN_vault_default and NOMAD_SECRETS_DIR are not set")
+		}
+		data, err := os.ReadFile(filepath.Join(secretsDir, "nomad_vault_default.jwt"))
+		if err != nil {
+			return "", fmt.Errorf("failed to read Nomad workload identity: %w", err)
+		}
+		jwt = strings.TrimSpace(string(data))
+	}
+
+	return c.login(mountOrDefault(auth.Mount, defaultNomadMount), jwtLoginData(jwt, auth.Role))
+}
+
+// login performs a login request against auth/<mount>/login and returns the client token
+func (c *Client) login(mount string, data map[string]interface{}) (string, error) {
+	// Never send a pre-existing token (e.g. from VAULT_TOKEN) with a login request
+	c.client.ClearToken()
+
+	path := "auth/" + strings.Trim(mount, "/") + "/login"
+	secret, err := c.client.Logical().Write(path, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to log in to Vault at %s: %w", path, err)
+	}
+	if secret == nil || secret.Auth == nil || secret.Auth.ClientToken == "" {
+		return "", fmt.Errorf("vault login at %s returned no token", path)
+	}
+
+	return secret.Auth.ClientToken, nil
+}
+
+func jwtLoginData(jwt string, role string) map[string]interface{} {
+	data := map[string]interface{}{
+		"jwt": jwt,
+	}
+	// Without a role Vault uses the default role of the auth mount
+	if role != "" {
+		data["role"] = role
+	}
+	return data
+}
+
+func mountOrDefault(mount string, fallback string) string {
+	if mount != "" {
+		return mount
+	}
+	return fallback
+}
@@ -0,0 +1,181 @@
+package vault
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// resetTokenCache clears tokens cached by previous tests
+func resetTokenCache() {
+	tokenCacheMu.Lock()
+	defer tokenCacheMu.Unlock()
+	tokenCache = map[string]string{}
+}
+
+func newLoginServer(t *testing.T, path string, logins *int32, body *map[string]interface{}) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			t.Errorf("Unexpected request path %s, expected %s", r.URL.Path, path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		atomic.AddInt32(logins, 1)
+		if body != nil {
+			json.NewDecoder(r.Body).Decode(body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"auth":{"client_token":"hvs.login-token"}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestLogin_TokenFromEnv(t *testing.T) {
+	resetTokenCache()
+	t.Setenv("DEVPOD_VAULT_TOKEN", "hvs.env-token")
+
+	client, err := NewClient("https://vault.example.com:8200", "")
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	if err := client.Login(AuthConfig{Method: AuthMethodToken}); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if client.client.Token() != "hvs.env-token" {
+		t.Errorf("Expected token hvs.env-token, got %s", client.client.Token())
+	}
+}
+
+func TestLogin_TokenMissing(t *testing.T) {
+	resetTokenCache()
+	t.Setenv("DEVPOD_VAULT_TOKEN", "")
+
+	client, err := NewClient("https://vault.example.com:8200", "")
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	if err := client.Login(AuthConfig{}); err == nil {
+		t.Error("Expected error when DEVPOD_VAULT_TOKEN is not set")
+	}
+}
+
+func TestLogin_TokenFile(t *testing.T) {
+	resetTokenCache()
+	tokenFile := filepath.Join(t.TempDir(), ".vault-token")
+	if err := os.WriteFile(tokenFile, []byte("hvs.file-token\n"), 0600); err != nil {
+		t.Fatalf("Failed to write token file: %v", err)
+	}
+
+	client, err := NewClient("https://vault.example.com:8200", "")
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	if err := client.Login(AuthConfig{Method: AuthMethodTokenFile, TokenFile: tokenFile}); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if client.client.Token() != "hvs.file-token" {
+		t.Errorf("Expected token hvs.file-token, got %s", client.client.Token())
+	}
+}
+
+func TestLogin_AppRoleIsCached(t *testing.T) {
+	resetTokenCache()
+	t.Setenv("DEVPOD_VAULT_ROLE_ID", "role-id")
+	t.Setenv("DEVPOD_VAULT_SECRET_ID", "secret-id")
+
+	var logins int32
+	var body map[string]interface{}
+	server := newLoginServer(t, "/v1/auth/approle/login", &logins, &body)
+
+	for i := 0; i < 2; i++ {
+		client, err := NewClient(server.URL, "")
+		if err != nil {
+			t.Fatalf("NewClient failed: %v", err)
+		}
+		if err := client.Login(AuthConfig{Method: AuthMethodAppRole}); err != nil {
+			t.Fatalf("Login failed: %v", err)
+		}
+		if client.client.Token() != "hvs.login-token" {
+			t.Errorf("Expected token hvs.login-token, got %s", client.client.Token())
+		}
+	}
+
+	if logins != 1 {
+		t.Errorf("Expected a single login request, got %d", logins)
+	}
+	if body["role_id"] != "role-id" || body["secret_id"] != "secret-id" {
+		t.Errorf("Unexpected login body: %v", body)
+	}
+}
+
+func TestLogin_JWTWithCustomMountAndRole(t *testing.T) {
+	resetTokenCache()
+	t.Setenv("DEVPOD_VAULT_JWT", "eyJ.test.jwt")
+
+	var logins int32
+	var body map[string]interface{}
+	server := newLoginServer(t, "/v1/auth/gitlab-ci/login", &logins, &body)
+
+	client, err := NewClient(server.URL, "")
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	err = client.Login(AuthConfig{Method: AuthMethodJWT, Mount: "gitlab-ci", Role: "devpod"})
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+
+	if body["jwt"] != "eyJ.test.jwt" || body["role"] != "devpod" {
+		t.Errorf("Unexpected login body: %v", body)
+	}
+}
+
+func TestLogin_NomadWorkloadIdentityFile(t *testing.T) {
+	resetTokenCache()
+	secretsDir := t.TempDir()
+	t.Setenv("NOMAD_TOKEN_vault_default", "")
+	t.Setenv("NOMAD_SECRETS_DIR", secretsDir)
+	if err := os.WriteFile(filepath.Join(secretsDir, "nomad_vault_default.jwt"), []byte("eyJ.nomad.jwt"), 0600); err != nil {
+		t.Fatalf("Failed to write identity file: %v", err)
+	}
+
+	var logins int32
+	var body map[string]interface{}
+	server := newLoginServer(t, "/v1/auth/jwt-nomad/login", &logins, &body)
+
+	client, err := NewClient(server.URL, "")
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if err := client.Login(AuthConfig{Method: AuthMethodNomad}); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+
+	if body["jwt"] != "eyJ.nomad.jwt" {
+		t.Errorf("Expected workload identity JWT in login body, got %v", body)
+	}
+	if _, ok := body["role"]; ok {
+		t.Errorf("Expected no role in login body when none is configured, got %v", body["role"])
+	}
+}
+
+func TestLogin_UnsupportedMethod(t *testing.T) {
+	resetTokenCache()
+
+	client, err := NewClient("https://vault.example.com:8200", "")
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	if err := client.Login(AuthConfig{Method: "ldap"}); err == nil {
+		t.Error("Expected error for unsupported auth method")
+	}
+}